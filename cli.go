@@ -0,0 +1,394 @@
+package main
+
+//
+// Command line parsing, getopt style: short options can be combined ('-sv'), a short option taking a value takes
+// the rest of the group or the next argument ('-Px_' or '-P x_'), long options take '--name value' or '--name=value'
+//
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"modelgenerator/common"
+)
+
+// exit codes, usage errors are reported with exitUsage and failed commands (like a model with errors) with exitFailed
+const (
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+// usageError is an error in the command line, reported with a hint to the help
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+//
+// Parsed command line
+//
+type commandLine struct {
+	options     common.Options
+	overrides   []func(options *common.Options) // options given on the command line, applied after the project file
+	command     *cliCommand
+	args        []string
+	projectFile string
+	force       bool
+	help        bool
+	version     bool
+}
+
+// set applies an option and keeps it to override the settings of a project file
+func (cl *commandLine) set(apply func(options *common.Options)) {
+	apply(&cl.options)
+	cl.overrides = append(cl.overrides, apply)
+}
+
+// cliOption is a command line option, options with an 'arg' name take a value
+type cliOption struct {
+	short   byte
+	long    string
+	arg     string
+	section string
+	help    string
+	apply   func(cl *commandLine, value string) error
+}
+
+func (option *cliOption) name() string {
+	switch {
+	case option.short == 0:
+		return "--" + option.long
+	case option.long == "":
+		return "-" + string(option.short)
+	}
+	return "-" + string(option.short) + "/--" + option.long
+}
+
+const (
+	sectionGeneral = "General Options"
+	sectionModel   = "Domain Model Options"
+	sectionDB      = "DB Layer Options"
+)
+
+var cliOptions = []cliOption{
+	{short: 'f', long: "from-version", arg: "num", section: sectionGeneral, help: "From Version, generates any class/field matching >= specified version (0 means as virgin)",
+		apply: func(cl *commandLine, value string) error {
+			version, err := strconv.Atoi(value)
+			if err != nil || version < 0 {
+				return usageErrorf("invalid version '%s' for --from-version, expected a number >= 0", value)
+			}
+			cl.set(func(options *common.Options) {
+				options.FromVersion = version
+				options.IsUpgrade = version > 0
+			})
+			return nil
+		}},
+	{short: 'p', long: "persistence", arg: "classes", section: sectionGeneral, help: "Generate persistence for the comma separated classes, or '-' for all",
+		apply: func(cl *commandLine, value string) error {
+			classes := strings.Split(value, ",")
+			cl.set(func(options *common.Options) {
+				options.DoPersistence = true
+				options.AllPersistenceClasses = classes
+				options.PersistenceClass = classes[0]
+			})
+			return nil
+		}},
+	{short: 's', long: "split", section: sectionGeneral, help: "split each type in separate file, -o, -O and --sql-output are the output directories (GO)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.SplitInFiles = true })
+			return nil
+		}},
+	{short: 'l', long: "language", arg: "lang", section: sectionGeneral, help: "specify output language (go/cpp/ts, see 'languages')",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.UseLanguage = value })
+			return nil
+		}},
+	{short: 'X', long: "language-option", arg: "name=value", section: sectionGeneral, help: "set an option of the language, a switch is set with only the name (see 'languages')",
+		apply: func(cl *commandLine, value string) error {
+			name, optionValue, hasValue := strings.Cut(value, "=")
+			if name == "" {
+				return usageErrorf("missing option name in '%s' for --language-option", value)
+			}
+			if !hasValue {
+				optionValue = "true"
+			}
+			cl.set(func(options *common.Options) { options.SetLanguageOption(name, optionValue) })
+			return nil
+		}},
+	{short: 'm', long: "member-prefix", arg: "prefix", section: sectionGeneral, help: "override model member prefix (use '!' to drop it)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.MemberPrefix = value })
+			return nil
+		}},
+	{short: 'G', long: "go-module", arg: "path", section: sectionGeneral, help: "Go module path for the packages of referenced includes (mode=\"reference\"), imported as <module>/<namespace>",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.GoModulePath = value })
+			return nil
+		}},
+	{short: 'T', long: "templates", arg: "dir", section: sectionGeneral, help: "template directory, the templates in <dir>/<language> override the generator templates with the same name",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.TemplateDirectory = value })
+			return nil
+		}},
+	{short: 'C', long: "project", arg: "file", section: sectionGeneral, help: "project file with the options and generation targets (default 'modelgen.yaml' if no model is given), options given override the project file",
+		apply: func(cl *commandLine, value string) error {
+			cl.projectFile = value
+			return nil
+		}},
+	{short: 'c', long: "converters", section: sectionModel, help: "generate convertes (to/from XML/JSON)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.Converters = true })
+			return nil
+		}},
+	{short: 'g', long: "no-getters", section: sectionModel, help: "disable getters/setters",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.GettersAndSetters = false })
+			return nil
+		}},
+	{short: 'o', long: "output", arg: "file", section: sectionModel, help: "specify output model file or '-' for stdout (default), the directory with -s",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.OutputName = value })
+			return nil
+		}},
+	{short: 'M', long: "marshalling", section: sectionModel, help: "generate marshalling code (defaults: CPP: off, GO: on, Typescript: on)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.CPPJson = true })
+			return nil
+		}},
+	{short: 'V', long: "validation", section: sectionModel, help: "generate Validate() methods from field constraints (GO)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.GenerateValidation = true })
+			return nil
+		}},
+	{short: 'P', long: "table-prefix", arg: "prefix", section: sectionDB, help: "Table name prefix (default is 'nagini_se_')",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.DBTablePrefix = value })
+			return nil
+		}},
+	{short: 'd', long: "drop", section: sectionDB, help: "Generate drop statements before create (default = false)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.GenerateDropStatement = true })
+			return nil
+		}},
+	{short: 'a', long: "validate-on-persist", section: sectionDB, help: "call Validate() from the generated Create/Update methods (implies -V)",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) {
+				options.ValidateOnPersist = true
+				options.GenerateValidation = true
+			})
+			return nil
+		}},
+	{short: 'O', long: "db-output", arg: "file", section: sectionDB, help: "specify output database go file or dir (if split in multiple files is true), default is 'db.go'",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.OutputDBName = value })
+			return nil
+		}},
+	{long: "sql-output", arg: "file", section: sectionDB, help: "write the DB create/upgrade script to the file instead of stdout, a file per table in the dir with -s",
+		apply: func(cl *commandLine, value string) error {
+			cl.set(func(options *common.Options) { options.OutputSQLName = value })
+			return nil
+		}},
+	{short: 'v', long: "verbose", help: "increase verbose output (default 0 - none)",
+		apply: func(cl *commandLine, value string) error {
+			cl.options.Verbose++
+			return nil
+		}},
+	{long: "check", help: "generate in memory and print the differences to the output files, exits with 1 if they are not up to date (nothing is written)",
+		apply: func(cl *commandLine, value string) error {
+			cl.options.CheckOutput = true
+			return nil
+		}},
+	{long: "force", help: "init: overwrite an existing project file",
+		apply: func(cl *commandLine, value string) error {
+			cl.force = true
+			return nil
+		}},
+	{short: 'h', long: "help", help: "this page",
+		apply: func(cl *commandLine, value string) error {
+			cl.help = true
+			return nil
+		}},
+	{long: "version", help: "print the version",
+		apply: func(cl *commandLine, value string) error {
+			cl.version = true
+			return nil
+		}},
+}
+
+func findShortOption(short byte) *cliOption {
+	for i := range cliOptions {
+		if cliOptions[i].short == short {
+			return &cliOptions[i]
+		}
+	}
+	return nil
+}
+
+func findLongOption(long string) *cliOption {
+	for i := range cliOptions {
+		if cliOptions[i].long == long {
+			return &cliOptions[i]
+		}
+	}
+	return nil
+}
+
+// cliCommand is a sub command, maxArgs < 0 allows any number of arguments
+type cliCommand struct {
+	name    string
+	args    string
+	help    string
+	minArgs int
+	maxArgs int
+	run     func(cl *commandLine) error
+}
+
+func findCommand(name string) *cliCommand {
+	for i := range cliCommands {
+		if cliCommands[i].name == name {
+			return &cliCommands[i]
+		}
+	}
+	return nil
+}
+
+//
+// Parses the arguments (without the program name), the first argument which is not an option is the command,
+// without a command the 'generate' command is used
+//
+func parseCommandLine(args []string, options common.Options) (*commandLine, error) {
+	cl := &commandLine{options: options}
+	positional := []string{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// value returns the value of an option, the given inline value or the next argument
+		value := func(option *cliOption, inline string, hasInline bool) (string, error) {
+			if hasInline {
+				return inline, nil
+			}
+			if i+1 >= len(args) {
+				return "", usageErrorf("option %s needs a value (<%s>)", option.name(), option.arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name, inline, hasInline := strings.Cut(arg[2:], "=")
+			option := findLongOption(name)
+			if option == nil {
+				return nil, usageErrorf("unknown option --%s", name)
+			}
+			optionValue := ""
+			if option.arg != "" {
+				var err error
+				if optionValue, err = value(option, inline, hasInline); err != nil {
+					return nil, err
+				}
+			} else if hasInline {
+				return nil, usageErrorf("option --%s doesn't take a value", name)
+			}
+			if err := option.apply(cl, optionValue); err != nil {
+				return nil, err
+			}
+		case len(arg) > 1 && arg[0] == '-':
+			for j := 1; j < len(arg); j++ {
+				option := findShortOption(arg[j])
+				if option == nil {
+					return nil, usageErrorf("unknown option -%c", arg[j])
+				}
+				optionValue := ""
+				if option.arg != "" {
+					var err error
+					if optionValue, err = value(option, arg[j+1:], j+1 < len(arg)); err != nil {
+						return nil, err
+					}
+					j = len(arg)
+				}
+				if err := option.apply(cl, optionValue); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if cl.help || cl.version {
+		return cl, nil
+	}
+	if len(positional) > 0 {
+		cl.command = findCommand(positional[0])
+	}
+	if cl.command != nil {
+		positional = positional[1:]
+	} else {
+		cl.command = findCommand("generate")
+	}
+	cl.args = positional
+	if len(cl.args) < cl.command.minArgs || (cl.command.maxArgs >= 0 && len(cl.args) > cl.command.maxArgs) {
+		return nil, usageErrorf("wrong number of arguments for '%s', usage: modelgenerator %s [options] %s", cl.command.name, cl.command.name, cl.command.args)
+	}
+	return cl, nil
+}
+
+func printHelp() {
+	fmt.Printf("%s %s - XML Data Model to Language structure converter\n", Name, Version)
+	fmt.Println("Usage: modelgenerator [<command>] [options] [<arguments>]")
+	fmt.Println("Commands")
+	for _, command := range cliCommands {
+		fmt.Printf("  %-13s %-33s : %s\n", command.name, command.args, command.help)
+	}
+	fmt.Println("  Without a command 'generate' is used, generate/validate/sql/migrate use the project file if no model is given.")
+	for _, section := range []string{sectionGeneral, sectionModel, sectionDB, ""} {
+		if section != "" {
+			fmt.Println(section)
+		}
+		for i := range cliOptions {
+			option := &cliOptions[i]
+			if option.section != section {
+				continue
+			}
+			names := "   "
+			if option.short != 0 {
+				names = "-" + string(option.short) + ","
+			}
+			if option.long != "" {
+				names += " --" + option.long
+			}
+			if option.arg != "" {
+				names += " <" + option.arg + ">"
+			}
+			fmt.Printf("  %-30s : %s\n", names, option.help)
+		}
+	}
+	fmt.Println("model : Data Model definition file (.xml, .yaml/.yml or .json)")
+	fmt.Println("")
+}
+
+// reportError prints the error and exits, usage errors refer to the help
+func reportError(err error) {
+	if err == errFailed {
+		os.Exit(exitFailed)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	if _, ok := err.(*usageError); ok {
+		fmt.Fprintf(os.Stderr, "Run 'modelgenerator --help' for usage\n")
+		os.Exit(exitUsage)
+	}
+	os.Exit(exitFailed)
+}
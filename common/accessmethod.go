@@ -9,6 +9,7 @@ type AccessMethod struct {
 	Setter    bool
 	IsList    bool
 	IsPointer bool
+	Nullable  bool
 	NoPersist bool
 	AutoID    bool
 	Name      string
@@ -11,6 +11,7 @@ type Options struct {
 	Filename              string
 	OutputName            string
 	OutputDBName          string
+	OutputSQLName         string // DB create script, written to stdout when empty or '-'
 	AllPersistenceClasses []string
 	PersistenceClass      string
 	DoPersistence         bool
@@ -18,17 +19,55 @@ type Options struct {
 	GenerateDropStatement bool
 	GettersAndSetters     bool
 	CPPJson               bool
+	GenerateValidation    bool   // Generate Validate() methods from field constraints
+	ValidateOnPersist     bool   // Call Validate() from the generated Create/Update methods
 	FromVersion           int    // Always assume from version 0
 	DocumentRootDirectory string // This is set by code to the root directory of the first document, relative for all includes
 	UseLanguage           string
 	Language              Language
 	MemberPrefix          string
+	GoModulePath          string            // Module path of the Go packages generated for referenced documents (include mode="reference")
+	LanguageOptions       map[string]string // Options of the language ('-X name=value'), see LanguageInfo.Options
+	TemplateDirectory     string            // Templates overriding the embedded code templates, see LoadTemplates
+	CheckOutput           bool              // Compare the generated code with the output files instead of writing them (--check)
 	CurrentDoc            *XMLDoc
 }
 
+// IsPersistenceClass returns true if persistence should be generated for the named class ('-p' option)
+func (options *Options) IsPersistenceClass(name string) bool {
+	if options.PersistenceClass == "-" {
+		return true
+	}
+	for _, className := range options.AllPersistenceClasses {
+		if className == name {
+			return true
+		}
+	}
+	return options.PersistenceClass == name
+}
+
+// Generator generates code from the resolved model, see BuildModel. An error is returned if the code can't be
+// generated, like a failing template override
 type Generator interface {
-	GenerateCode(doc XMLDoc, options *Options) string
+	GenerateCode(model *Model, options *Options) (string, error)
+}
+
+// GeneratedFile is a file of a split output (SplitInFiles), the name is relative to the output directory
+type GeneratedFile struct {
+	Name string
+	Code string
 }
+
+// FileGenerator is implemented by the generators which can split the output in a file per define (-s)
+type FileGenerator interface {
+	GenerateFiles(model *Model, options *Options) ([]GeneratedFile, error)
+	// IsGeneratedFile returns true if a file in the output directory is named like the files of the generator, the
+	// output directory can be shared with other generators
+	IsGeneratedFile(name string) bool
+}
+
+// Language creates the generators of a target language, a generator which is not supported is nil.
+// Languages are registered with RegisterLanguage
 type Language interface {
 	GetModelGenerator() Generator
 	GetCrudGenerator() Generator
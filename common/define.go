@@ -1 +1,136 @@
 package common
+
+// PrimaryKeys returns the fields making up the primary key of a class, in declaration order.
+// Key fields are marked with primarykey="true", several marked fields gives a composite key.
+// If no field is marked the first field is used as key (the behaviour before the attribute existed).
+func (define *XMLDefine) PrimaryKeys() []*XMLDataTypeField {
+	var keys []*XMLDataTypeField
+	for i := range define.Fields {
+		if define.Fields[i].PrimaryKey {
+			keys = append(keys, &define.Fields[i])
+		}
+	}
+	if len(keys) == 0 && len(define.Fields) > 0 {
+		keys = append(keys, &define.Fields[0])
+	}
+	return keys
+}
+
+// IsPrimaryKey returns true if the field is part of the primary key of the class
+func (define *XMLDefine) IsPrimaryKey(field *XMLDataTypeField) bool {
+	for _, key := range define.PrimaryKeys() {
+		if key.Name == field.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Inheritance strategies for persisting inherited fields ('inheritance' attribute of a class with 'inherits')
+const (
+	// InheritanceFlat copies the columns of the parent table into the table of the class (the default)
+	InheritanceFlat = "flat"
+	// InheritanceJoined stores the inherited fields in the parent table, the class table holds the key and the declared fields
+	InheritanceJoined = "joined"
+)
+
+// IsJoined returns true if the inherited fields are stored in the parent table
+func (define *XMLDefine) IsJoined() bool {
+	return define.Inherits != "" && define.Inheritance == InheritanceJoined
+}
+
+// InheritanceChain returns the define followed by the classes it inherits from, nearest first.
+// The chain ends at an unknown parent or where it would loop.
+func (doc *XMLDoc) InheritanceChain(define *XMLDefine) []*XMLDefine {
+	chain := []*XMLDefine{}
+	seen := make(map[string]bool)
+	for current := define; current != nil && !seen[current.Name]; current = doc.FindDefine(current.Inherits) {
+		seen[current.Name] = true
+		chain = append(chain, current)
+		if current.Inherits == "" {
+			break
+		}
+	}
+	return chain
+}
+
+// fieldRef is a field with the define declaring it
+type fieldRef struct {
+	define *XMLDefine
+	field  *XMLDataTypeField
+}
+
+func (ref fieldRef) String() string {
+	return ref.define.Name + "::" + ref.field.Name
+}
+
+// valueCycle returns the fields through which a class contains itself by value: class fields which are not a list or
+// pointer, declared by the class or a class it inherits. Empty if the class doesn't contain itself, the GO and C++ type
+// of such a class can't be declared.
+func (doc *XMLDoc) valueCycle(define *XMLDefine) []fieldRef {
+	visited := make(map[string]bool)
+	var visit func(current *XMLDefine, path []fieldRef) []fieldRef
+	visit = func(current *XMLDefine, path []fieldRef) []fieldRef {
+		visited[current.Name] = true
+		for _, owner := range doc.InheritanceChain(current) {
+			if owner == define && current != define {
+				// the class is embedded as parent of a class it contains
+				return path
+			}
+			for i := range owner.Fields {
+				field := &owner.Fields[i]
+				if field.IsList || field.IsPointer {
+					continue
+				}
+				target := doc.FindDefine(field.Type)
+				if target == nil || target.Type != "class" {
+					continue
+				}
+				step := append(append([]fieldRef{}, path...), fieldRef{define: owner, field: field})
+				if target == define {
+					return step
+				}
+				if !visited[target.Name] {
+					if cycle := visit(target, step); cycle != nil {
+						return cycle
+					}
+				}
+			}
+		}
+		return nil
+	}
+	return visit(define, nil)
+}
+
+// ClassKeys returns the primary key of a class. A class declaring no key field uses the key of the class it inherits from,
+// if no class in the chain declares a key the first field of the base class is used (see PrimaryKeys).
+func (doc *XMLDoc) ClassKeys(define *XMLDefine) []*XMLDataTypeField {
+	chain := doc.InheritanceChain(define)
+	for _, current := range chain {
+		var keys []*XMLDataTypeField
+		for i := range current.Fields {
+			if current.Fields[i].PrimaryKey {
+				keys = append(keys, &current.Fields[i])
+			}
+		}
+		if len(keys) > 0 {
+			return keys
+		}
+	}
+	return chain[len(chain)-1].PrimaryKeys()
+}
+
+// FindClassField returns the declared or inherited field with the given name or nil if there is none
+func (doc *XMLDoc) FindClassField(define *XMLDefine, name string) *XMLDataTypeField {
+	for _, current := range doc.InheritanceChain(define) {
+		if field := current.FindField(name); field != nil {
+			return field
+		}
+	}
+	return nil
+}
+
+// Documentation returns the lines of the 'description' attribute followed by the <doc> text, nil if the define is undocumented
+func (define *XMLDefine) Documentation() []string {
+	return documentationLines(define.Description, define.Doc)
+}
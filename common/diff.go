@@ -0,0 +1,184 @@
+package common
+
+//
+// Compares two versions of a model, used by the 'diff' command to review a model change
+//
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffDocuments returns the differences between two loaded documents, one line per added ('+'), removed ('-')
+// or changed ('~') define, field, enum value or index. Removed defines and fields count as not present.
+func DiffDocuments(from *XMLDoc, to *XMLDoc) []string {
+	lines := []string{}
+	fromDefines := diffDefines(from)
+	toDefines := diffDefines(to)
+	for _, define := range fromDefines {
+		if findDiffDefine(toDefines, define.Name) == nil {
+			lines = append(lines, fmt.Sprintf("- %s %s", define.Type, define.Name))
+		}
+	}
+	for _, define := range toDefines {
+		old := findDiffDefine(fromDefines, define.Name)
+		if old == nil {
+			lines = append(lines, fmt.Sprintf("+ %s %s", define.Type, define.Name))
+			continue
+		}
+		lines = append(lines, diffDefine(old, define)...)
+	}
+	return lines
+}
+
+// diffDefines returns the defines of the document which are not removed
+func diffDefines(doc *XMLDoc) []*XMLDefine {
+	defines := []*XMLDefine{}
+	for i := range doc.Defines {
+		if !doc.Defines[i].IsRemoved() {
+			defines = append(defines, &doc.Defines[i])
+		}
+	}
+	return defines
+}
+
+func findDiffDefine(defines []*XMLDefine, name string) *XMLDefine {
+	for _, define := range defines {
+		if define.Name == name {
+			return define
+		}
+	}
+	return nil
+}
+
+func diffDefine(from *XMLDefine, to *XMLDefine) []string {
+	lines := []string{}
+	changed := func(what string, old string, new string) {
+		if old != new {
+			lines = append(lines, fmt.Sprintf("~ %s %s: %s '%s' -> '%s'", to.Type, to.Name, what, old, new))
+		}
+	}
+	changed("type", from.Type, to.Type)
+	changed("inherits", from.Inherits, to.Inherits)
+	changed("inheritance", from.Inheritance, to.Inheritance)
+	changed("nopersist", fmt.Sprint(from.SkipPersistance), fmt.Sprint(to.SkipPersistance))
+
+	kind := "field"
+	if to.Type == "enum" {
+		kind = "value"
+	}
+	fromFields := diffFields(from)
+	toFields := diffFields(to)
+	for _, field := range fromFields {
+		if findDiffField(toFields, field.Name) == nil {
+			lines = append(lines, fmt.Sprintf("- %s %s::%s", kind, from.Name, field.Name))
+		}
+	}
+	for _, field := range toFields {
+		old := findDiffField(fromFields, field.Name)
+		if old == nil {
+			lines = append(lines, fmt.Sprintf("+ %s %s::%s (%s)", kind, to.Name, field.Name, describeDiffField(to, field)))
+		} else if oldDesc, newDesc := describeDiffField(from, old), describeDiffField(to, field); oldDesc != newDesc {
+			lines = append(lines, fmt.Sprintf("~ %s %s::%s: %s -> %s", kind, to.Name, field.Name, oldDesc, newDesc))
+		}
+	}
+
+	lines = append(lines, diffIndexes(from, to, from.Indexes, to.Indexes, false)...)
+	lines = append(lines, diffIndexes(from, to, from.Uniques, to.Uniques, true)...)
+	return lines
+}
+
+// diffFields returns the fields (or enum values) of the define which are not removed
+func diffFields(define *XMLDefine) []*XMLDataTypeField {
+	declared := define.Fields
+	if define.Type == "enum" {
+		declared = define.Ints
+	}
+	fields := []*XMLDataTypeField{}
+	for i := range declared {
+		if !declared[i].IsRemoved() {
+			fields = append(fields, &declared[i])
+		}
+	}
+	return fields
+}
+
+func findDiffField(fields []*XMLDataTypeField, name string) *XMLDataTypeField {
+	for _, field := range fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// describeDiffField describes the properties of a field which matter for the generated code and the DB schema
+func describeDiffField(define *XMLDefine, field *XMLDataTypeField) string {
+	if define.Type == "enum" {
+		return fmt.Sprintf("value %d", field.Value)
+	}
+	parts := []string{field.Type}
+	if field.IsList {
+		parts[0] = "[]" + parts[0]
+	}
+	if field.IsPointer {
+		parts[0] = "*" + parts[0]
+	}
+	flag := func(set bool, name string) {
+		if set {
+			parts = append(parts, name)
+		}
+	}
+	flag(define.IsPrimaryKey(field), "primarykey")
+	flag(field.Nullable, "nullable")
+	flag(field.DBAutoID, "dbautoid")
+	flag(field.SkipPersistance, "nopersist")
+	if field.FieldSize > 0 {
+		parts = append(parts, fmt.Sprintf("fieldsize=%d", field.FieldSize))
+	}
+	if field.DBSize > 0 {
+		parts = append(parts, fmt.Sprintf("dbsize=%d", field.DBSize))
+	}
+	if field.HasDefault() {
+		parts = append(parts, fmt.Sprintf("default='%s'", field.DefaultValue()))
+	}
+	if field.References != "" {
+		parts = append(parts, fmt.Sprintf("references=%s", field.References))
+	}
+	if field.Relation != "" {
+		parts = append(parts, fmt.Sprintf("relation=%s", field.Relation))
+	}
+	return strings.Join(parts, " ")
+}
+
+func diffIndexes(from *XMLDefine, to *XMLDefine, fromIndexes []XMLIndex, toIndexes []XMLIndex, unique bool) []string {
+	kind := "index"
+	if unique {
+		kind = "unique"
+	}
+	lines := []string{}
+	find := func(define *XMLDefine, indexes []XMLIndex, name string) *XMLIndex {
+		for i := range indexes {
+			if indexes[i].IndexName(define, unique) == name {
+				return &indexes[i]
+			}
+		}
+		return nil
+	}
+	for i := range fromIndexes {
+		name := fromIndexes[i].IndexName(from, unique)
+		if find(to, toIndexes, name) == nil {
+			lines = append(lines, fmt.Sprintf("- %s %s::%s", kind, from.Name, name))
+		}
+	}
+	for i := range toIndexes {
+		name := toIndexes[i].IndexName(to, unique)
+		old := find(from, fromIndexes, name)
+		if old == nil {
+			lines = append(lines, fmt.Sprintf("+ %s %s::%s (%s)", kind, to.Name, name, toIndexes[i].Fields))
+		} else if old.Fields != toIndexes[i].Fields || old.Prefix != toIndexes[i].Prefix {
+			lines = append(lines, fmt.Sprintf("~ %s %s::%s: (%s) -> (%s)", kind, to.Name, name, old.Fields, toIndexes[i].Fields))
+		}
+	}
+	return lines
+}
@@ -2,12 +2,13 @@ package common
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 func (field *XMLDataTypeField) dump() {
 	fmt.Printf("  Name: %s\n", field.Name)
-	fmt.Printf("  Default: %s\n", field.Default)
+	fmt.Printf("  Default: %s\n", field.DefaultValue())
 }
 
 //TypeMapping maps a field's type to potential mappings from the specific table
@@ -61,6 +62,106 @@ func (field *XMLDataTypeField) GetTypeMappingLang(typeMappings []XMLTypeMapping,
 	return nil
 }
 
+// Documentation returns the lines of the 'description' attribute followed by the <doc> text, nil if the field is undocumented
+func (field *XMLDataTypeField) Documentation() []string {
+	return documentationLines(field.Description, field.Doc)
+}
+
+// documentationLines splits descriptions into trimmed lines, the descriptions are separated by an empty line.
+// Empty lines at the start and the end are dropped.
+func documentationLines(descriptions ...string) []string {
+	var lines []string
+	for _, description := range descriptions {
+		if strings.TrimSpace(description) == "" {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+// IsNullable returns true if the field can hold NULL/no value, lists are never nullable (an empty list is used instead)
+func (field *XMLDataTypeField) IsNullable() bool {
+	return field.Nullable && !field.IsList
+}
+
+// HasDefault returns true if the field declares a default value, an empty default (default="") included
+func (field *XMLDataTypeField) HasDefault() bool {
+	return field.Default != nil
+}
+
+// DefaultValue returns the default value of the field, empty if there is none (see HasDefault)
+func (field *XMLDataTypeField) DefaultValue() string {
+	if field.Default == nil {
+		return ""
+	}
+	return *field.Default
+}
+
+// Go types which min/max constraints apply to
+var numericGoTypes = map[string]bool{
+	"byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// Sizes of the Go integer types, 'int' and 'uint' are taken as 64 bits
+var integerGoTypeBits = map[string]int{
+	"byte": 8, "rune": 32,
+	"int": 64, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": 64, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+}
+
+// IsIntegerLimit returns true if a min/max value is an integer in the range of the Go integer type
+func IsIntegerLimit(goType string, value string) bool {
+	bits := integerGoTypeBits[goType]
+	var err error
+	if goType == "byte" || strings.HasPrefix(goType, "uint") {
+		_, err = strconv.ParseUint(value, 10, bits)
+	} else {
+		_, err = strconv.ParseInt(value, 10, bits)
+	}
+	return err == nil
+}
+
+// IsIntegerGoType returns true for the builtin Go integer types
+func IsIntegerGoType(goType string) bool {
+	return integerGoTypeBits[goType] != 0
+}
+
+// IsNumericGoType returns true for the builtin Go number types
+func IsNumericGoType(goType string) bool {
+	return numericGoTypes[goType]
+}
+
+// HasConstraints returns true if any validation constraint is declared on the field
+func (field *XMLDataTypeField) HasConstraints() bool {
+	return field.Required || field.Min != "" || field.Max != "" || field.MinLen != 0 || field.MaxLen != 0 || field.Pattern != ""
+}
+
+// EffectiveMaxLen returns the maximum length of the field, 'maxlen' or for strings the field size used in the DB
+func (field *XMLDataTypeField) EffectiveMaxLen(doc *XMLDoc) int {
+	if field.MaxLen != 0 || field.IsList {
+		return field.MaxLen
+	}
+	if field.TypeMapping(doc.GOTypeMappings) != "string" {
+		return 0
+	}
+	if field.FieldSize != 0 {
+		return field.FieldSize
+	}
+	if mapping := field.GetTypeMappingLang(doc.DBTypeMappings, ""); mapping != nil {
+		return mapping.FieldSize
+	}
+	return 0
+}
+
 //
 // TODO: These should be moved out of here
 //
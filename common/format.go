@@ -0,0 +1,129 @@
+package common
+
+//
+// Model file formats, XML (default), YAML and JSON - chosen by the file extension
+//
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported model file formats
+const (
+	FormatXML  = "xml"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// DocumentFormat returns the format of a model file from its extension, anything unknown is XML
+func DocumentFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	return FormatXML
+}
+
+// ParseDocument parses a model file in the format given by the file name, includes are not loaded
+func ParseDocument(filename string, data []byte) (XMLDoc, error) {
+	var doc XMLDoc
+	switch DocumentFormat(filename) {
+	case FormatYAML:
+		var file modelFile
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && err != io.EOF {
+			return doc, fmt.Errorf("%s: %w", filename, err)
+		}
+		doc = file.toXMLDoc()
+	case FormatJSON:
+		var file modelFile
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return doc, fmt.Errorf("%s: %w", filename, err)
+		}
+		doc = file.toXMLDoc()
+	default:
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return doc, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	doc.SetSourceFile(filename)
+	return doc, nil
+}
+
+// ReadDocumentFile reads and parses a single model file, includes are not loaded (see LoadDocument)
+func ReadDocumentFile(filename string) (XMLDoc, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+	return ParseDocument(filename, data)
+}
+
+// xmlDocOut is used when writing a document, dbcontrol is left out when empty
+type xmlDocOut struct {
+	XMLName         xml.Name         `xml:"doc"`
+	Namespace       string           `xml:"namespace,attr,omitempty"`
+	DBSchema        string           `xml:"dbschema,attr,omitempty"`
+	Includes        []XMLInclude     `xml:"include"`
+	Imports         []XMLImport      `xml:"imports>package"`
+	DBTypeMappings  []XMLTypeMapping `xml:"dbtypemappings>map"`
+	GOTypeMappings  []XMLTypeMapping `xml:"gotypemappings>map"`
+	AnyTypeMappings []XMLTypeMapping `xml:"anytypemappings>map"`
+	DBControl       *XMLDBControl    `xml:"dbcontrol"`
+	Defines         []XMLDefine      `xml:"define"`
+}
+
+// FormatDocument writes a single (not merged) document in the format given by the file name
+func FormatDocument(doc *XMLDoc, filename string) ([]byte, error) {
+	switch DocumentFormat(filename) {
+	case FormatYAML:
+		buffer := bytes.Buffer{}
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fromXMLDoc(doc)); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(fromXMLDoc(doc), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+
+	out := xmlDocOut{
+		Namespace:       doc.Namespace,
+		DBSchema:        doc.DBSchema,
+		Imports:         doc.Imports,
+		DBTypeMappings:  doc.DBTypeMappings,
+		GOTypeMappings:  doc.GOTypeMappings,
+		AnyTypeMappings: doc.AnyTypeMappings,
+		Defines:         doc.Defines,
+	}
+	for _, include := range doc.Includes {
+		out.Includes = append(out.Includes, XMLInclude{Filename: strings.TrimSpace(include.Filename), Mode: include.Mode})
+	}
+	if doc.DBControl != (XMLDBControl{}) {
+		out.DBControl = &doc.DBControl
+	}
+	data, err := xml.MarshalIndent(out, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,452 @@
+package common
+
+//
+// Extraction of a model from existing Go structs (and their int64 const enums), parsed with go/ast
+//
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Model types for well known Go types, other qualified types get a type mapping of their own
+var extractQualifiedTypes = map[string]string{
+	"time.Time": "time",
+	"uuid.UUID": "guid",
+}
+
+// goExtractor holds the state while extracting a model from Go source
+type goExtractor struct {
+	fset    *token.FileSet
+	options *Options
+	doc     XMLDoc
+
+	structs  map[string]*ast.TypeSpec
+	named    map[string]*ast.TypeSpec // all other named types
+	values   map[string][]XMLDataTypeField
+	files    map[string]*ast.File // file declaring a type
+	order    []string             // type names in declaration order
+	wanted   map[string]bool
+	done     map[string]bool
+	used     map[string]bool
+	mappings map[string]bool
+}
+
+// ExtractGoFile extracts a model from the Go files of a package, source is a file or a directory (test files are skipped)
+func ExtractGoFile(source string, typeNames []string, options *Options) (XMLDoc, error) {
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	info, err := os.Stat(source)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+	if info.IsDir() {
+		pkgs, err := parser.ParseDir(fset, source, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			return XMLDoc{}, err
+		}
+		if len(pkgs) != 1 {
+			return XMLDoc{}, fmt.Errorf("%s: expected a single package, found %d", source, len(pkgs))
+		}
+		for _, pkg := range pkgs {
+			names := []string{}
+			for name := range pkg.Files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				files = append(files, pkg.Files[name])
+			}
+		}
+	} else {
+		file, err := parser.ParseFile(fset, source, nil, 0)
+		if err != nil {
+			return XMLDoc{}, err
+		}
+		files = append(files, file)
+	}
+	return ExtractGo(fset, files, typeNames, options)
+}
+
+// ExtractGo creates a model from the struct types of the parsed files, typeNames selects the structs (all structs if empty).
+// Structs used by the selected structs (field types and embedded structs) are extracted as well, and so are the
+// enums: int64 based types with constants. Slices become lists, pointers 'ispointer' and 'xml' struct tags 'xmlattrib'.
+func ExtractGo(fset *token.FileSet, files []*ast.File, typeNames []string, options *Options) (XMLDoc, error) {
+	extractor := goExtractor{
+		fset:     fset,
+		options:  options,
+		structs:  make(map[string]*ast.TypeSpec),
+		named:    make(map[string]*ast.TypeSpec),
+		values:   make(map[string][]XMLDataTypeField),
+		files:    make(map[string]*ast.File),
+		wanted:   make(map[string]bool),
+		done:     make(map[string]bool),
+		used:     make(map[string]bool),
+		mappings: make(map[string]bool),
+	}
+	for _, file := range files {
+		extractor.collect(file)
+		if extractor.doc.Namespace == "" {
+			extractor.doc.Namespace = file.Name.Name
+		}
+	}
+
+	if len(typeNames) == 0 {
+		for _, name := range extractor.order {
+			if _, ok := extractor.structs[name]; ok {
+				typeNames = append(typeNames, name)
+			}
+		}
+	}
+	for _, name := range typeNames {
+		if _, ok := extractor.structs[name]; ok {
+			extractor.wanted[name] = true
+		} else if extractor.isEnum(name) {
+			extractor.wanted[name] = true
+		} else {
+			return XMLDoc{}, fmt.Errorf("no struct or int64 enum named '%s' found", name)
+		}
+	}
+	if len(extractor.wanted) == 0 {
+		return XMLDoc{}, fmt.Errorf("no struct types found")
+	}
+
+	// Extracting a struct can add more wanted types, repeat until nothing is added
+	for added := true; added; {
+		added = false
+		for _, name := range extractor.order {
+			if extractor.wanted[name] && !extractor.done[name] {
+				extractor.done[name] = true
+				extractor.extract(name)
+				added = true
+			}
+		}
+	}
+	// Keep the declaration order of the source
+	defines := []XMLDefine{}
+	for _, name := range extractor.order {
+		for _, define := range extractor.doc.Defines {
+			if define.Name == name {
+				defines = append(defines, define)
+			}
+		}
+	}
+	extractor.doc.Defines = defines
+	addImportTypeMappings(&extractor.doc, extractor.used, false)
+
+	doc := extractor.doc
+	if len(files) > 0 {
+		doc.SetSourceFile(fset.Position(files[0].Pos()).Filename)
+	}
+	return doc, nil
+}
+
+func (extractor *goExtractor) position(pos token.Pos) SourcePos {
+	position := extractor.fset.Position(pos)
+	return SourcePos{File: position.Filename, Line: position.Line}
+}
+
+func (extractor *goExtractor) warnf(pos token.Pos, format string, args ...interface{}) {
+	log.Printf("!WARNING!: %s: %s\n", extractor.position(pos), fmt.Sprintf(format, args...))
+}
+
+// collect records the type declarations and the enum constants of a file
+func (extractor *goExtractor) collect(file *ast.File) {
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		switch genDecl.Tok {
+		case token.TYPE:
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				name := typeSpec.Name.Name
+				if _, ok := typeSpec.Type.(*ast.StructType); ok {
+					extractor.structs[name] = typeSpec
+				} else {
+					extractor.named[name] = typeSpec
+				}
+				extractor.files[name] = file
+				extractor.order = append(extractor.order, name)
+			}
+		case token.CONST:
+			extractor.collectConstants(genDecl)
+		}
+	}
+}
+
+// collectConstants evaluates a const block, typed constants are kept as enum values of their type.
+// Implicit repetition of the previous expression and iota are supported.
+func (extractor *goExtractor) collectConstants(decl *ast.GenDecl) {
+	var typeName string
+	var exprs []ast.Expr
+	known := make(map[string]int64)
+	for iota, spec := range decl.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		if valueSpec.Values != nil {
+			exprs = valueSpec.Values
+			typeName = ""
+			if ident, ok := valueSpec.Type.(*ast.Ident); ok {
+				typeName = ident.Name
+			}
+		}
+		for i, name := range valueSpec.Names {
+			if i >= len(exprs) {
+				break
+			}
+			value, ok := evalConstant(exprs[i], int64(iota), known)
+			if !ok {
+				continue
+			}
+			known[name.Name] = value
+			if name.Name == "_" || typeName == "" {
+				continue
+			}
+			extractor.values[typeName] = append(extractor.values[typeName], XMLDataTypeField{
+				Name:  name.Name,
+				Value: int(value),
+				Pos:   extractor.position(name.Pos()),
+			})
+		}
+	}
+}
+
+// evalConstant evaluates a constant integer expression
+func evalConstant(expr ast.Expr, iota int64, known map[string]int64) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return 0, false
+		}
+		value, err := strconv.ParseInt(e.Value, 0, 64)
+		return value, err == nil
+	case *ast.Ident:
+		if e.Name == "iota" {
+			return iota, true
+		}
+		value, ok := known[e.Name]
+		return value, ok
+	case *ast.ParenExpr:
+		return evalConstant(e.X, iota, known)
+	case *ast.CallExpr:
+		// conversion like Colour(1)
+		if len(e.Args) == 1 {
+			return evalConstant(e.Args[0], iota, known)
+		}
+	case *ast.UnaryExpr:
+		value, ok := evalConstant(e.X, iota, known)
+		if ok && e.Op == token.SUB {
+			return -value, true
+		}
+		return value, ok && e.Op == token.ADD
+	case *ast.BinaryExpr:
+		x, okX := evalConstant(e.X, iota, known)
+		y, okY := evalConstant(e.Y, iota, known)
+		if !okX || !okY {
+			return 0, false
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, true
+		case token.SUB:
+			return x - y, true
+		case token.MUL:
+			return x * y, true
+		case token.QUO:
+			if y != 0 {
+				return x / y, true
+			}
+		case token.SHL:
+			return x << uint(y), true
+		case token.SHR:
+			return x >> uint(y), true
+		case token.OR:
+			return x | y, true
+		case token.AND:
+			return x & y, true
+		}
+	}
+	return 0, false
+}
+
+// isEnum returns true for an int64 based type with constants
+func (extractor *goExtractor) isEnum(name string) bool {
+	spec, ok := extractor.named[name]
+	if !ok {
+		return false
+	}
+	ident, ok := spec.Type.(*ast.Ident)
+	return ok && ident.Name == "int64" && len(extractor.values[name]) > 0
+}
+
+// extract adds the define for a struct or enum
+func (extractor *goExtractor) extract(name string) {
+	if extractor.options.Verbose > 0 {
+		log.Printf("Extracting type '%s'\n", name)
+	}
+	if extractor.isEnum(name) {
+		spec := extractor.named[name]
+		define := XMLDefine{Type: "enum", Name: name, Ints: extractor.values[name], Pos: extractor.position(spec.Pos())}
+		extractor.doc.Defines = append(extractor.doc.Defines, define)
+		return
+	}
+
+	spec := extractor.structs[name]
+	define := XMLDefine{Type: "class", Name: name, Pos: extractor.position(spec.Pos())}
+	for _, astField := range spec.Type.(*ast.StructType).Fields.List {
+		if len(astField.Names) == 0 {
+			// embedded struct
+			embedded := astField.Type
+			if star, ok := embedded.(*ast.StarExpr); ok {
+				embedded = star.X
+			}
+			ident, ok := embedded.(*ast.Ident)
+			if !ok || extractor.structs[ident.Name] == nil {
+				extractor.warnf(astField.Pos(), "embedded field in '%s' is not a struct of the package, skipped", name)
+				continue
+			}
+			if define.Inherits != "" {
+				extractor.warnf(astField.Pos(), "'%s' embeds several structs, only '%s' is inherited", name, define.Inherits)
+				continue
+			}
+			define.Inherits = ident.Name
+			extractor.wanted[ident.Name] = true
+			continue
+		}
+		for _, fieldName := range astField.Names {
+			// Unexported fields are internal state, not part of the model (and not marshalled)
+			if !fieldName.IsExported() {
+				if extractor.options.Verbose > 0 {
+					log.Printf("Skipping unexported field '%s::%s'\n", name, fieldName.Name)
+				}
+				continue
+			}
+			field := XMLDataTypeField{Name: fieldName.Name, Pos: extractor.position(fieldName.Pos())}
+			if !extractor.fieldType(&field, name, astField.Type, extractor.files[name]) {
+				continue
+			}
+			if astField.Tag != nil {
+				tag, _ := strconv.Unquote(astField.Tag.Value)
+				field.XMLAttrib = reflect.StructTag(tag).Get("xml")
+			}
+			extractor.used[field.Type] = true
+			define.Fields = append(define.Fields, field)
+		}
+	}
+	if len(define.Fields) == 0 && define.Inherits == "" {
+		extractor.warnf(spec.Pos(), "struct '%s' has no fields", name)
+	}
+	extractor.doc.Defines = append(extractor.doc.Defines, define)
+}
+
+// fieldType sets the type of a field from the Go type expression, returns false if the type can't be expressed in the model
+func (extractor *goExtractor) fieldType(field *XMLDataTypeField, className string, expr ast.Expr, file *ast.File) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		if field.IsPointer {
+			extractor.warnf(t.Pos(), "pointer to pointer '%s::%s' is not supported, skipped", className, field.Name)
+			return false
+		}
+		field.IsPointer = true
+		return extractor.fieldType(field, className, t.X, file)
+	case *ast.ArrayType:
+		if field.IsList || field.IsPointer {
+			extractor.warnf(t.Pos(), "nested list or pointer to list '%s::%s' is not supported, skipped", className, field.Name)
+			return false
+		}
+		if t.Len != nil {
+			extractor.warnf(t.Pos(), "array '%s::%s' is extracted as a list", className, field.Name)
+		}
+		field.IsList = true
+		return extractor.fieldType(field, className, t.Elt, file)
+	case *ast.Ident:
+		if builtinTypes[t.Name] {
+			field.Type = t.Name
+			return true
+		}
+		if _, ok := extractor.structs[t.Name]; ok || extractor.isEnum(t.Name) {
+			field.Type = t.Name
+			extractor.wanted[t.Name] = true
+			return true
+		}
+		if spec, ok := extractor.named[t.Name]; ok {
+			// named type, like 'type Email string', use the underlying type
+			if underlying, ok := spec.Type.(*ast.Ident); ok && builtinTypes[underlying.Name] {
+				if extractor.options.Verbose > 0 {
+					log.Printf("Field '%s::%s' of type '%s' is extracted as '%s'\n", className, field.Name, t.Name, underlying.Name)
+				}
+				field.Type = underlying.Name
+				return true
+			}
+		}
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		goType := pkg.Name + "." + t.Sel.Name
+		modelType, ok := extractQualifiedTypes[goType]
+		if !ok {
+			modelType = pkg.Name + t.Sel.Name
+		}
+		field.Type = modelType
+		extractor.addGoMapping(modelType, goType)
+		extractor.addImport(pkg.Name, file)
+		return true
+	}
+	extractor.warnf(expr.Pos(), "type of '%s::%s' is not supported, skipped", className, field.Name)
+	return false
+}
+
+// addGoMapping adds the go type mapping for a qualified type, the well known types are added with the DB mappings
+func (extractor *goExtractor) addGoMapping(modelType string, goType string) {
+	if _, ok := importTypeMappings[modelType]; ok || extractor.mappings[modelType] {
+		return
+	}
+	extractor.mappings[modelType] = true
+	extractor.doc.GOTypeMappings = append(extractor.doc.GOTypeMappings, XMLTypeMapping{FromType: modelType, ToType: goType})
+}
+
+// addImport adds the import of the file for a package name
+func (extractor *goExtractor) addImport(pkgName string, file *ast.File) {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := importPackageName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != pkgName {
+			continue
+		}
+		imp := XMLImport{Package: path}
+		if name != path[strings.LastIndex(path, "/")+1:] {
+			imp.Package = name + " " + path
+		}
+		if !hasImport(extractor.doc.Imports, imp) {
+			extractor.doc.Imports = append(extractor.doc.Imports, imp)
+		}
+		return
+	}
+	extractor.warnf(file.Pos(), "no import found for package '%s'", pkgName)
+}
+
+// importPackageName guesses the package name of an import path, like 'uuid' for 'github.com/satori/go.uuid'
+func importPackageName(path string) string {
+	name := path[strings.LastIndex(path, "/")+1:]
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "go."), "go-")
+	if idx := strings.Index(name, "."); idx > 0 {
+		name = name[:idx]
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package common
+
+//
+// Type mappings shared by the importers (import-schema, extract)
+//
+
+// DB and GO type mappings added for the types used by an imported model, the same as the usual common declarations
+var importTypeMappings = map[string]struct {
+	dbType    string
+	fieldSize int
+	goType    string
+	imports   []XMLImport
+}{
+	"guid":    {"varchar(36)", 0, "uuid.UUID", []XMLImport{{Package: "uuid github.com/satori/go.uuid", DisablePersistence: true}}},
+	"time":    {"datetime", 0, "time.Time", []XMLImport{{Package: "time"}}},
+	"string":  {"varchar(%d)", 128, "", nil},
+	"int32":   {"int(11)", 0, "", nil},
+	"int64":   {"bigint(20)", 0, "", nil},
+	"float32": {"float", 0, "", nil},
+	"float64": {"double", 0, "", nil},
+}
+
+// The order the mappings are added in
+var importTypeOrder = []string{"guid", "time", "string", "int32", "int64", "float32", "float64"}
+
+// addImportTypeMappings adds the type mappings for the used types and the enums to the document, with the imports they need if withImports is set
+func addImportTypeMappings(doc *XMLDoc, used map[string]bool, withImports bool) {
+	for _, modelType := range importTypeOrder {
+		if !used[modelType] {
+			continue
+		}
+		mapping := importTypeMappings[modelType]
+		doc.DBTypeMappings = append(doc.DBTypeMappings, XMLTypeMapping{FromType: modelType, ToType: mapping.dbType, FieldSize: mapping.fieldSize})
+		if mapping.goType != "" {
+			doc.GOTypeMappings = append(doc.GOTypeMappings, XMLTypeMapping{FromType: modelType, ToType: mapping.goType})
+		}
+		if !withImports {
+			continue
+		}
+		for _, imp := range mapping.imports {
+			if !hasImport(doc.Imports, imp) {
+				doc.Imports = append(doc.Imports, imp)
+			}
+		}
+	}
+	// Enums are stored as their value
+	for _, define := range doc.Defines {
+		if define.Type == "enum" {
+			doc.DBTypeMappings = append(doc.DBTypeMappings, XMLTypeMapping{FromType: define.Name, ToType: "int(11)"})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IndexColumn is a field in an index with an optional prefix length
+type IndexColumn struct {
+	Field  string
+	Prefix int
+}
+
+// Columns parses the 'fields' attribute of an index, a comma separated list of field names.
+// A field can have a prefix length like 'Path(64)', otherwise the 'prefix' attribute of the index is used.
+func (index *XMLIndex) Columns() ([]IndexColumn, error) {
+	columns := []IndexColumn{}
+	for _, spec := range strings.Split(index.Fields, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		column := IndexColumn{Field: spec, Prefix: index.Prefix}
+		if idx := strings.Index(spec, "("); idx >= 0 {
+			if !strings.HasSuffix(spec, ")") {
+				return nil, fmt.Errorf("invalid index field '%s', expected 'Field(length)'", spec)
+			}
+			prefix, err := strconv.Atoi(spec[idx+1 : len(spec)-1])
+			if err != nil || prefix <= 0 {
+				return nil, fmt.Errorf("invalid prefix length in index field '%s'", spec)
+			}
+			column.Field = strings.TrimSpace(spec[:idx])
+			column.Prefix = prefix
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("index has no fields")
+	}
+	return columns, nil
+}
+
+// IndexName returns the name of the index, the 'name' attribute or 'idx_<class>_<fields>' ('uq_' for unique indexes)
+func (index *XMLIndex) IndexName(define *XMLDefine, unique bool) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	prefix := "idx"
+	if unique {
+		prefix = "uq"
+	}
+	name := prefix + "_" + strings.ToLower(define.Name)
+	columns, _ := index.Columns()
+	for _, column := range columns {
+		name += "_" + strings.ToLower(column.Field)
+	}
+	return name
+}
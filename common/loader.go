@@ -0,0 +1,303 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// documentLoader loads a document and all its includes, includes are resolved relative to the including file
+type documentLoader struct {
+	options    *Options
+	chain      []string           // absolute names of the documents currently being loaded, root first
+	loaded     map[string]bool    // absolute names of all documents loaded so far
+	references map[string]*XMLDoc // documents included with mode="reference" by absolute name, shared by all loaders
+}
+
+// IncludeError is returned when an include can't be loaded, it holds the chain of documents leading to the include
+type IncludeError struct {
+	Filename string
+	Chain    []string
+	Err      error
+}
+
+func (e *IncludeError) Error() string {
+	msg := fmt.Sprintf("unable to include '%s': %s", e.Filename, e.Err)
+	for i := len(e.Chain) - 1; i >= 0; i-- {
+		msg += fmt.Sprintf("\n  included from %s", e.Chain[i])
+	}
+	return msg
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// LoadDocument loads a document and recursively merges all included documents into it.
+// Documents included with mode="reference" are not merged, they are loaded on their own and kept in XMLDoc.References.
+func LoadDocument(options *Options, filename string) (XMLDoc, error) {
+	loader := documentLoader{
+		options:    options,
+		loaded:     make(map[string]bool),
+		references: make(map[string]*XMLDoc),
+	}
+	absName, err := filepath.Abs(filename)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+	return loader.load(filename, absName)
+}
+
+func (loader *documentLoader) load(filename string, absName string) (XMLDoc, error) {
+	data, err := ioutil.ReadFile(absName)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+
+	// The format is given by the extension, includes can mix formats
+	doc, err := ParseDocument(filename, data)
+	if err != nil {
+		return doc, err
+	}
+
+	loader.loaded[absName] = true
+	loader.chain = append(loader.chain, absName)
+	defer func() {
+		loader.chain = loader.chain[:len(loader.chain)-1]
+	}()
+
+	// Includes are merged first and the content of the document itself last, so the document
+	// overrides the type mappings and settings of its includes, like a later include overrides an earlier one
+	merged := XMLDoc{
+		Namespace: doc.Namespace,
+		Includes:  doc.Includes,
+		Filename:  doc.Filename,
+	}
+	for i := range merged.Includes {
+		include := &merged.Includes[i]
+		incFilename := strings.TrimSpace(include.Filename)
+		incAbsName, err := loader.resolveInclude(absName, incFilename)
+		if err != nil {
+			return doc, &IncludeError{Filename: incFilename, Chain: loader.relativeChain(), Err: err}
+		}
+
+		if loader.options.Verbose > 0 {
+			log.Printf("Including: %s\n", incFilename)
+			log.Printf("Pathname: %s\n", incAbsName)
+		}
+
+		for _, name := range loader.chain {
+			if name == incAbsName {
+				return doc, &IncludeError{Filename: incFilename, Chain: loader.relativeChain(), Err: fmt.Errorf("include cycle, the file is already being included")}
+			}
+		}
+		switch include.Mode {
+		case "", IncludeMerge:
+		case IncludeReference:
+			refDoc, err := loader.loadReference(incAbsName)
+			if err != nil {
+				if _, ok := err.(*IncludeError); ok {
+					return doc, err
+				}
+				return doc, &IncludeError{Filename: incFilename, Chain: loader.relativeChain(), Err: err}
+			}
+			include.Document = *refDoc
+			addReference(&merged, refDoc)
+			continue
+		default:
+			return doc, &IncludeError{Filename: incFilename, Chain: loader.relativeChain(), Err: fmt.Errorf("unknown include mode '%s' (merge or reference)", include.Mode)}
+		}
+		if loader.loaded[incAbsName] {
+			// Included more than once (like a common file included by several includes), the content is already merged
+			if loader.options.Verbose > 0 {
+				log.Printf("Skipping '%s', already included\n", incFilename)
+			}
+			continue
+		}
+
+		incDoc, err := loader.load(loader.displayName(incAbsName), incAbsName)
+		if err != nil {
+			if _, ok := err.(*IncludeError); ok {
+				return doc, err
+			}
+			return doc, &IncludeError{Filename: incFilename, Chain: loader.relativeChain(), Err: err}
+		}
+		include.Document = incDoc
+		if err := MergeDocuments(&merged, &incDoc, loader.options); err != nil {
+			return doc, err
+		}
+	}
+	if err := MergeDocuments(&merged, &doc, loader.options); err != nil {
+		return doc, err
+	}
+	return merged, nil
+}
+
+// loadReference loads a document included with mode="reference". The document is loaded on its own, the documents
+// it merges are not shared with the including document, and only once no matter how many documents reference it.
+func (loader *documentLoader) loadReference(incAbsName string) (*XMLDoc, error) {
+	if refDoc, ok := loader.references[incAbsName]; ok {
+		return refDoc, nil
+	}
+	refLoader := documentLoader{
+		options:    loader.options,
+		chain:      append([]string{}, loader.chain...),
+		loaded:     make(map[string]bool),
+		references: loader.references,
+	}
+	refDoc, err := refLoader.load(loader.displayName(incAbsName), incAbsName)
+	if err != nil {
+		return nil, err
+	}
+	loader.references[incAbsName] = &refDoc
+	return &refDoc, nil
+}
+
+// addReference adds a referenced document unless it is already referenced
+func addReference(doc *XMLDoc, refDoc *XMLDoc) {
+	for _, existing := range doc.References {
+		if existing == refDoc {
+			return
+		}
+	}
+	doc.References = append(doc.References, refDoc)
+}
+
+// resolveInclude returns the absolute name of an include. Includes are relative to the including file,
+// for backwards compatibility the root document directory is tried if the file doesn't exist there.
+func (loader *documentLoader) resolveInclude(includingAbsName string, incFilename string) (string, error) {
+	if filepath.IsAbs(incFilename) {
+		return incFilename, nil
+	}
+	candidates := []string{filepath.Join(filepath.Dir(includingAbsName), incFilename)}
+	if loader.options.DocumentRootDirectory != "" {
+		candidates = append(candidates, filepath.Join(loader.options.DocumentRootDirectory, incFilename))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.Clean(candidate), nil
+		}
+	}
+	return "", fmt.Errorf("file not found (%s)", candidates[0])
+}
+
+// displayName returns the name relative to the root document directory if possible, used in diagnostics
+func (loader *documentLoader) displayName(absName string) string {
+	if loader.options.DocumentRootDirectory != "" {
+		if rel, err := filepath.Rel(loader.options.DocumentRootDirectory, absName); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return absName
+}
+
+func (loader *documentLoader) relativeChain() []string {
+	chain := []string{}
+	for _, name := range loader.chain {
+		chain = append(chain, loader.displayName(name))
+	}
+	return chain
+}
+
+// MergeDocuments merges the defines, imports, references and settings of a document into the documents merged so far.
+//
+// A define already present is an error unless the new define is marked with override="true", in which case it
+// replaces the existing one. Type mappings replace existing mappings for the same language and type, imports
+// already present are skipped and non empty settings replace the existing settings.
+func MergeDocuments(dst *XMLDoc, src *XMLDoc, options *Options) error {
+	verbose := options != nil && options.Verbose > 0
+
+	for _, imp := range src.Imports {
+		if !hasImport(dst.Imports, imp) {
+			dst.Imports = append(dst.Imports, imp)
+		} else if verbose {
+			log.Printf("Skipping duplicate import %s\n", strings.TrimSpace(imp.Package))
+		}
+	}
+
+	for _, refDoc := range src.References {
+		addReference(dst, refDoc)
+	}
+
+	conflicts := []string{}
+	existing := make(map[string]int)
+	for i := range dst.Defines {
+		existing[dst.Defines[i].Name] = i
+	}
+	for _, define := range src.Defines {
+		i, ok := existing[define.Name]
+		if !ok {
+			dst.Defines = append(dst.Defines, define)
+			continue
+		}
+		if !define.Override {
+			conflicts = append(conflicts, fmt.Sprintf("%s: '%s' is already defined at %s, use override=\"true\" to replace it", define.Pos, define.Name, dst.Defines[i].Pos))
+			continue
+		}
+		if verbose {
+			log.Printf("%s: '%s' overrides the define at %s\n", define.Pos, define.Name, dst.Defines[i].Pos)
+		}
+		dst.Defines[i] = define
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting defines:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+
+	dst.DBTypeMappings = mergeTypeMappings("dbtypemappings", dst.DBTypeMappings, src.DBTypeMappings, verbose)
+	dst.GOTypeMappings = mergeTypeMappings("gotypemappings", dst.GOTypeMappings, src.GOTypeMappings, verbose)
+	dst.AnyTypeMappings = mergeTypeMappings("anytypemappings", dst.AnyTypeMappings, src.AnyTypeMappings, verbose)
+
+	if src.DBSchema != "" {
+		dst.DBSchema = src.DBSchema
+	}
+	if src.DBControl.DBName != "" {
+		dst.DBControl.DBName = src.DBControl.DBName
+	}
+	if src.DBControl.Schema != "" {
+		dst.DBControl.Schema = src.DBControl.Schema
+	}
+	if src.DBControl.Host != "" {
+		dst.DBControl.Host = src.DBControl.Host
+	}
+	if src.DBControl.Password != "" {
+		dst.DBControl.Password = src.DBControl.Password
+	}
+	if src.DBControl.User != "" {
+		dst.DBControl.User = src.DBControl.User
+	}
+
+	return nil
+}
+
+func hasImport(imports []XMLImport, imp XMLImport) bool {
+	for _, existing := range imports {
+		if strings.TrimSpace(existing.Package) == strings.TrimSpace(imp.Package) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTypeMappings adds the mappings of src to dst, a mapping for a language and type already in dst is replaced.
+// Duplicates within src are kept, they are reported by the validator.
+func mergeTypeMappings(section string, dst []XMLTypeMapping, src []XMLTypeMapping, verbose bool) []XMLTypeMapping {
+	existing := make(map[string]int)
+	for i := range dst {
+		existing[dst[i].Lang+":"+dst[i].FromType] = i
+	}
+	for _, mapping := range src {
+		i, ok := existing[mapping.Lang+":"+mapping.FromType]
+		if !ok {
+			dst = append(dst, mapping)
+			continue
+		}
+		if verbose {
+			log.Printf("%s: %s mapping '%s' to '%s' overrides '%s' from %s\n", mapping.Pos, section, mapping.FromType, mapping.ToType, dst[i].ToType, dst[i].Pos)
+		}
+		dst[i] = mapping
+	}
+	return dst
+}
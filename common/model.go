@@ -0,0 +1,485 @@
+package common
+
+//
+// Resolved model, built from the merged (and validated) document and shared by all generators.
+// Field types are resolved to their defines, inheritance is flattened and keys and relations are resolved,
+// so generators don't have to look things up in the XML structures themselves.
+//
+
+import (
+	"fmt"
+)
+
+// Language names for MappedType, 'go' and 'db' use gotypemappings and dbtypemappings,
+// any other language uses the anytypemappings with a matching 'lang' attribute
+const (
+	LangGo         = "go"
+	LangDB         = "db"
+	LangCpp        = "cpp"
+	LangTypeScript = "ts"
+)
+
+// Model is the resolved document
+type Model struct {
+	Doc          *XMLDoc
+	Namespace    string
+	Types        []*Type // all defines in declaration order, types of referenced documents and removed types are not included
+	RemovedTypes []*Type // removed defines in declaration order, see XMLDefine.IsRemoved
+	Version      int     // the latest version in the document, see XMLDoc.LatestVersion
+
+	types map[string]*Type
+}
+
+// Type is a resolved define (class or enum)
+type Type struct {
+	*XMLDefine
+
+	Document      *XMLDoc  // the document declaring the type, a referenced document for types which are not generated (see IsExternal)
+	Parent        *Type    // resolved 'inherits', nil if the class doesn't inherit
+	Fields        []*Field // the fields declared by the define (not inherited), removed fields are only included while deprecated
+	RemovedFields []*Field // the removed fields declared by the define, see XMLDataTypeField.IsRemoved
+	Keys          []*Field // primary key fields, see XMLDoc.ClassKeys (may be inherited)
+
+	model *Model
+}
+
+// Field is a resolved field of a class
+type Field struct {
+	*XMLDataTypeField
+
+	Owner        *Type     // the type declaring the field
+	UserType     *Type     // the define the field type refers to, nil for builtin and mapped types
+	IsKey        bool      // part of the primary key of the owner (or of a class inheriting the key)
+	Referenced   *Field    // resolved 'references', the referenced key field
+	Related      *Relation // resolved 'relation'
+	ResolveError error     // set if 'references' or 'relation' could not be resolved
+}
+
+// Relation is a resolved 'onetomany' or 'manytomany' list field
+type Relation struct {
+	Kind   string // RelationOneToMany or RelationManyToMany
+	Target *Type  // the class on the other side of the relation
+
+	// onetomany: the field in Target referencing the owner
+	MappedBy *Field
+
+	// manytomany: the keys stored in the join table and the join table column names
+	OwnerKey     *Field
+	TargetKey    *Field
+	OwnerColumn  string
+	TargetColumn string
+}
+
+// BuildModel resolves a document, the document should be validated first.
+// Anything which can't be resolved is left unresolved (nil), relation errors are kept in Field.ResolveError.
+// Types of referenced documents are only resolved by name, their fields are resolved when their own document is generated.
+func BuildModel(doc *XMLDoc) *Model {
+	model := &Model{
+		Doc:       doc,
+		Namespace: doc.Namespace,
+		Version:   doc.LatestVersion(),
+		types:     make(map[string]*Type),
+	}
+
+	for i := range doc.Defines {
+		t := &Type{XMLDefine: &doc.Defines[i], Document: doc, model: model}
+		if t.IsRemoved() {
+			model.RemovedTypes = append(model.RemovedTypes, t)
+		} else {
+			model.Types = append(model.Types, t)
+		}
+		if _, ok := model.types[t.Name]; !ok {
+			model.types[t.Name] = t
+		}
+	}
+	// A local define hides a referenced define with the same name
+	for _, refDoc := range doc.References {
+		for i := range refDoc.Defines {
+			t := &Type{XMLDefine: &refDoc.Defines[i], Document: refDoc, model: model}
+			if _, ok := model.types[t.Name]; !ok {
+				model.types[t.Name] = t
+			}
+		}
+	}
+
+	// Keys may be inherited, fields are looked up by their XML declaration
+	fields := make(map[*XMLDataTypeField]*Field)
+	for _, t := range append(append([]*Type{}, model.Types...), model.RemovedTypes...) {
+		t.Parent = model.FindType(t.Inherits)
+		for i := range t.XMLDefine.Fields {
+			field := &Field{XMLDataTypeField: &t.XMLDefine.Fields[i], Owner: t}
+			field.UserType = model.FindType(field.Type)
+			if field.IsRemoved() {
+				t.RemovedFields = append(t.RemovedFields, field)
+			}
+			if !field.IsRemoved() || field.IsDeprecated() {
+				t.Fields = append(t.Fields, field)
+			}
+			fields[field.XMLDataTypeField] = field
+		}
+	}
+	for _, t := range model.Types {
+		for _, key := range doc.ClassKeys(t.XMLDefine) {
+			fields[key].IsKey = true
+			t.Keys = append(t.Keys, fields[key])
+		}
+	}
+
+	// References and relations point to fields of other types, all types must be resolved first
+	for _, t := range model.Types {
+		for _, field := range t.Fields {
+			if field.IsRemoved() {
+				continue
+			}
+			if field.References != "" {
+				if _, refField, err := doc.ResolveReference(field.XMLDataTypeField); err != nil {
+					field.ResolveError = err
+				} else {
+					field.Referenced = fields[refField]
+				}
+			}
+			switch field.Relation {
+			case RelationOneToMany:
+				child, childField, err := doc.ResolveOneToMany(t.XMLDefine, field.XMLDataTypeField)
+				if err != nil {
+					field.ResolveError = err
+					continue
+				}
+				target := model.FindType(child.Name)
+				field.Related = &Relation{Kind: RelationOneToMany, Target: target, MappedBy: target.FindField(childField.Name)}
+			case RelationManyToMany:
+				parentKey, child, childKey, err := doc.ResolveManyToMany(t.XMLDefine, field.XMLDataTypeField)
+				if err != nil {
+					field.ResolveError = err
+					continue
+				}
+				target := model.FindType(child.Name)
+				relation := &Relation{Kind: RelationManyToMany, Target: target, OwnerKey: fields[parentKey], TargetKey: fields[childKey]}
+				relation.OwnerColumn, relation.TargetColumn = JoinColumnNames(parentKey, field.XMLDataTypeField, childKey)
+				field.Related = relation
+			}
+		}
+	}
+	return model
+}
+
+// FindType returns the type with the given name or nil if there is none
+func (model *Model) FindType(name string) *Type {
+	if name == "" {
+		return nil
+	}
+	return model.types[name]
+}
+
+// UsedReferences returns the referenced documents declaring the types of any field, in include order
+func (model *Model) UsedReferences() []*XMLDoc {
+	used := make(map[*XMLDoc]bool)
+	for _, t := range model.Types {
+		for _, field := range t.Fields {
+			if field.UserType != nil && field.UserType.IsExternal() {
+				used[field.UserType.Document] = true
+			}
+		}
+	}
+	docs := []*XMLDoc{}
+	for _, refDoc := range model.Doc.References {
+		if used[refDoc] {
+			docs = append(docs, refDoc)
+		}
+	}
+	return docs
+}
+
+// UsedTypesFrom returns the names of the types of a referenced document used by any field, in declaration order
+func (model *Model) UsedTypesFrom(refDoc *XMLDoc) []string {
+	used := make(map[string]bool)
+	for _, t := range model.Types {
+		for _, field := range t.Fields {
+			if field.UserType != nil && field.UserType.Document == refDoc {
+				used[field.UserType.Name] = true
+			}
+		}
+	}
+	names := []string{}
+	for i := range refDoc.Defines {
+		if used[refDoc.Defines[i].Name] {
+			names = append(names, refDoc.Defines[i].Name)
+		}
+	}
+	return names
+}
+
+// SortTypesByDependency orders the types so a referenced class comes before the classes referencing it,
+// see XMLDoc.SortDefinesByDependency
+func (model *Model) SortTypesByDependency() ([]*Type, error) {
+	defines, err := model.Doc.SortDefinesByDependency()
+	types := []*Type{}
+	for _, define := range defines {
+		if !define.IsRemoved() {
+			types = append(types, model.FindType(define.Name))
+		}
+	}
+	return types, err
+}
+
+// SortTypesByInheritance orders the types so a class comes after the class it inherits from, otherwise the declaration order is kept.
+// Languages requiring a complete base class declaration (C++, TypeScript) generate the types in this order.
+func (model *Model) SortTypesByInheritance() []*Type {
+	types := []*Type{}
+	done := make(map[*Type]bool)
+	var add func(t *Type)
+	add = func(t *Type) {
+		if done[t] {
+			return
+		}
+		// Mark before the parent is added, an inheritance cycle (reported by the validator) ends here
+		done[t] = true
+		if t.Parent != nil {
+			add(t.Parent)
+		}
+		types = append(types, t)
+	}
+	for _, t := range model.Types {
+		add(t)
+	}
+	return types
+}
+
+// IsExternal returns true for types of referenced documents, they are used but not generated
+func (t *Type) IsExternal() bool {
+	return t.Document != t.model.Doc
+}
+
+// QualifiedName returns the name of the type in the language, types of referenced documents are qualified by their namespace
+func (t *Type) QualifiedName(lang string) string {
+	if !t.IsExternal() {
+		return t.Name
+	}
+	switch lang {
+	case LangGo:
+		return t.Document.PackageName() + "." + t.Name
+	case LangCpp:
+		return t.Document.CppNamespace() + "::" + t.Name
+	}
+	return t.Name
+}
+
+// IsClass returns true for class defines
+func (t *Type) IsClass() bool {
+	return t.Type == "class"
+}
+
+// IsEnum returns true for enum defines
+func (t *Type) IsEnum() bool {
+	return t.Type == "enum"
+}
+
+// FindField returns the declared field with the given name or nil if there is none
+func (t *Type) FindField(name string) *Field {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// AllFields returns the inherited fields (base class first) followed by the declared fields
+func (t *Type) AllFields() []*Field {
+	chain := []*Type{}
+	seen := make(map[*Type]bool)
+	for current := t; current != nil && !seen[current]; current = current.Parent {
+		seen[current] = true
+		chain = append([]*Type{current}, chain...)
+	}
+	fields := []*Field{}
+	for _, current := range chain {
+		fields = append(fields, current.Fields...)
+	}
+	return fields
+}
+
+// PersistedFields returns the declared fields stored as columns, in declaration order
+func (t *Type) PersistedFields() []*Field {
+	fields := []*Field{}
+	for _, field := range t.Fields {
+		if field.IsPersisted() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Table is a DB table holding (part of) the persisted fields of a class
+type Table struct {
+	Type    *Type    // the class the table is named after
+	Fields  []*Field // the columns in creation order, the key columns of a joined table come first
+	Keys    []*Field // the primary key, for a joined table the key of the class it inherits from
+	Sources []*Type  // the classes declaring the indexes stored in the table
+	Joined  bool     // the table extends the last table of the parent, the keys reference the parent table
+}
+
+// IsJoined returns true if the inherited fields are stored in the table of the parent class
+func (t *Type) IsJoined() bool {
+	return t.Parent != nil && t.XMLDefine.IsJoined()
+}
+
+// Tables returns the tables storing the persisted fields of the class, the base table first.
+// A class without joined inheritance has a single table holding the inherited columns, see 'inheritance'.
+func (t *Type) Tables() []*Table {
+	chain := []*Type{}
+	seen := make(map[*Type]bool)
+	for current := t; current != nil && !seen[current]; current = current.Parent {
+		seen[current] = true
+		chain = append([]*Type{current}, chain...)
+	}
+
+	tables := []*Table{}
+	for _, current := range chain {
+		switch {
+		case len(tables) == 0:
+			tables = append(tables, &Table{Type: current, Fields: current.PersistedFields(), Keys: current.Keys, Sources: []*Type{current}})
+		case current.IsJoined():
+			keys := tables[len(tables)-1].Keys
+			fields := append(append([]*Field{}, keys...), current.PersistedFields()...)
+			tables = append(tables, &Table{Type: current, Fields: fields, Keys: keys, Sources: []*Type{current}, Joined: true})
+		default:
+			last := tables[len(tables)-1]
+			fields := append(append([]*Field{}, last.Fields...), current.PersistedFields()...)
+			sources := append(append([]*Type{}, last.Sources...), current)
+			tables[len(tables)-1] = &Table{Type: current, Fields: fields, Keys: current.Keys, Sources: sources, Joined: last.Joined}
+		}
+	}
+	return tables
+}
+
+// Table returns the table named after the class, see Tables
+func (t *Type) Table() *Table {
+	tables := t.Tables()
+	return tables[len(tables)-1]
+}
+
+// IsKey returns true if the field is part of the primary key of the table
+func (table *Table) IsKey(field *Field) bool {
+	for _, key := range table.Keys {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueFields returns the columns of the table which are not part of the primary key
+func (table *Table) ValueFields() []*Field {
+	fields := []*Field{}
+	for _, field := range table.Fields {
+		if !table.IsKey(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// DeclaredFields returns the columns of the table which are not copied from the parent table (the keys of a joined table)
+func (table *Table) DeclaredFields() []*Field {
+	if !table.Joined {
+		return table.Fields
+	}
+	return table.ValueFields()
+}
+
+// ReferencesTo returns the persisted fields of the type referencing the target class
+func (t *Type) ReferencesTo(target *Type) []*Field {
+	fields := []*Field{}
+	for _, field := range t.Fields {
+		if field.Referenced != nil && field.IsPersisted() && field.Referenced.Owner == target {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// AccessMethods returns the getter/setter meta info for the declared fields, with the field type mapped for the language
+func (t *Type) AccessMethods(lang string) []AccessMethod {
+	methods := []AccessMethod{}
+	for _, field := range t.Fields {
+		methods = append(methods, AccessMethod{
+			Define:    t.XMLDefine,
+			Getter:    true,
+			Setter:    true,
+			IsList:    field.IsList,
+			IsPointer: field.IsPointer,
+			Nullable:  field.IsNullable(),
+			NoPersist: field.SkipPersistance,
+			AutoID:    field.DBAutoID,
+			Name:      field.Name,
+			Type:      field.MappedType(lang),
+		})
+	}
+	return methods
+}
+
+// IsClass returns true if the field type is a class define
+func (field *Field) IsClass() bool {
+	return field.UserType != nil && field.UserType.IsClass()
+}
+
+// IsEnum returns true if the field type is an enum define
+func (field *Field) IsEnum() bool {
+	return field.UserType != nil && field.UserType.IsEnum()
+}
+
+// mappings returns the type mappings used for a language
+func (field *Field) mappings(lang string) []XMLTypeMapping {
+	doc := field.Owner.model.Doc
+	switch lang {
+	case LangGo:
+		return doc.GOTypeMappings
+	case LangDB:
+		return doc.DBTypeMappings
+	}
+	return doc.AnyTypeMappings
+}
+
+// MappedType returns the field type in the language, the type itself if there is no mapping.
+// A type of a referenced document is qualified by its namespace, in the DB the mappings of the referenced document apply.
+func (field *Field) MappedType(lang string) string {
+	if userType := field.UserType; userType != nil && userType.IsExternal() && field.Mapping(lang) == nil {
+		if lang == LangDB {
+			return field.TypeMapping(userType.Document.DBTypeMappings)
+		}
+		return userType.QualifiedName(lang)
+	}
+	switch lang {
+	case LangGo, LangDB:
+		return field.TypeMapping(field.mappings(lang))
+	}
+	return field.TypeMappingLang(field.mappings(lang), lang)
+}
+
+// Mapping returns the type mapping of the field in the language, nil if the type isn't mapped
+func (field *Field) Mapping(lang string) *XMLTypeMapping {
+	switch lang {
+	case LangGo, LangDB:
+		return field.GetTypeMappingLang(field.mappings(lang), "")
+	}
+	return field.GetTypeMappingLang(field.mappings(lang), lang)
+}
+
+// IsDeprecated returns true for a field removed in the latest version of the model, the language generators keep
+// the field for that version (marked as deprecated) so code using it can be migrated. The field is not persisted.
+func (field *Field) IsDeprecated() bool {
+	return field.ToVersion > 0 && field.ToVersion == field.Owner.model.Version
+}
+
+// DeprecationNote returns the note for a deprecated field, like 'removed in version 3', empty if the field isn't deprecated
+func (field *Field) DeprecationNote() string {
+	if !field.IsDeprecated() {
+		return ""
+	}
+	return fmt.Sprintf("removed in version %d", field.ToVersion)
+}
+
+func (field *Field) String() string {
+	return fmt.Sprintf("%s::%s", field.Owner.Name, field.Name)
+}
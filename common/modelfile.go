@@ -0,0 +1,520 @@
+package common
+
+//
+// YAML/JSON model format, the same model as the XML document with less ceremony.
+// Field types carry the list/pointer flags, '[]*Address' is a list of pointers to Address.
+//
+//   namespace: resource
+//   include: [common.xml, {file: shared.xml, mode: reference}]
+//   imports: [time, {package: uuid github.com/satori/go.uuid, no_persistence: true}]
+//   gotypemappings:
+//     - {from: guid, to: uuid.UUID}
+//   defines:
+//     - type: class
+//       name: User
+//       fields:
+//         - {name: ID, type: guid, primarykey: true}
+//         - {name: Tags, type: '[]string'}
+//
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type modelFile struct {
+	Namespace       string             `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	DBSchema        string             `yaml:"dbschema,omitempty" json:"dbschema,omitempty"`
+	Include         []modelInclude     `yaml:"include,omitempty" json:"include,omitempty"`
+	Imports         []modelImport      `yaml:"imports,omitempty" json:"imports,omitempty"`
+	DBTypeMappings  []modelTypeMapping `yaml:"dbtypemappings,omitempty" json:"dbtypemappings,omitempty"`
+	GOTypeMappings  []modelTypeMapping `yaml:"gotypemappings,omitempty" json:"gotypemappings,omitempty"`
+	AnyTypeMappings []modelTypeMapping `yaml:"anytypemappings,omitempty" json:"anytypemappings,omitempty"`
+	DBControl       *modelDBControl    `yaml:"dbcontrol,omitempty" json:"dbcontrol,omitempty"`
+	Defines         []modelDefine      `yaml:"defines,omitempty" json:"defines,omitempty"`
+}
+
+// modelImport is either a package string or an object when 'no_persistence' is set
+type modelImport struct {
+	Package       string `yaml:"package" json:"package"`
+	NoPersistence bool   `yaml:"no_persistence,omitempty" json:"no_persistence,omitempty"`
+}
+
+// modelInclude is either a file name or an object when 'mode' is set
+type modelInclude struct {
+	File string `yaml:"file" json:"file"`
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+}
+
+type modelTypeMapping struct {
+	Lang      string `yaml:"lang,omitempty" json:"lang,omitempty"`
+	From      string `yaml:"from" json:"from"`
+	To        string `yaml:"to" json:"to"`
+	Encode    string `yaml:"encode,omitempty" json:"encode,omitempty"`
+	Decode    string `yaml:"decode,omitempty" json:"decode,omitempty"`
+	FieldSize int    `yaml:"fieldsize,omitempty" json:"fieldsize,omitempty"`
+
+	line int
+}
+
+type modelDBControl struct {
+	Host     string `yaml:"host,omitempty" json:"host,omitempty"`
+	DBName   string `yaml:"dbname,omitempty" json:"dbname,omitempty"`
+	Schema   string `yaml:"schema,omitempty" json:"schema,omitempty"`
+	User     string `yaml:"user,omitempty" json:"user,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+}
+
+type modelDefine struct {
+	Type        string           `yaml:"type" json:"type"`
+	Name        string           `yaml:"name" json:"name"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Inherits    string           `yaml:"inherits,omitempty" json:"inherits,omitempty"`
+	Inheritance string           `yaml:"inheritance,omitempty" json:"inheritance,omitempty"`
+	DBSchema    string           `yaml:"dbschema,omitempty" json:"dbschema,omitempty"`
+	Prefix      string           `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	NoPersist   bool             `yaml:"nopersist,omitempty" json:"nopersist,omitempty"`
+	Override    bool             `yaml:"override,omitempty" json:"override,omitempty"`
+	FromVersion int              `yaml:"fromversion,omitempty" json:"fromversion,omitempty"`
+	ToVersion   int              `yaml:"toversion,omitempty" json:"toversion,omitempty"`
+	Removed     bool             `yaml:"removed,omitempty" json:"removed,omitempty"`
+	Fields      []modelField     `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Values      []modelEnumValue `yaml:"values,omitempty" json:"values,omitempty"`
+	Indexes     []modelIndex     `yaml:"indexes,omitempty" json:"indexes,omitempty"`
+	Uniques     []modelIndex     `yaml:"uniques,omitempty" json:"uniques,omitempty"`
+
+	line int
+}
+
+type modelField struct {
+	Name        string      `yaml:"name" json:"name"`
+	Type        string      `yaml:"type" json:"type"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	IsList      bool        `yaml:"islist,omitempty" json:"islist,omitempty"`
+	IsPointer   bool        `yaml:"ispointer,omitempty" json:"ispointer,omitempty"`
+	Default     *modelValue `yaml:"default,omitempty" json:"default,omitempty"`
+	DBSize      int         `yaml:"dbsize,omitempty" json:"dbsize,omitempty"`
+	FieldSize   int         `yaml:"fieldsize,omitempty" json:"fieldsize,omitempty"`
+	FromVersion int         `yaml:"fromversion,omitempty" json:"fromversion,omitempty"`
+	ToVersion   int         `yaml:"toversion,omitempty" json:"toversion,omitempty"`
+	Removed     bool        `yaml:"removed,omitempty" json:"removed,omitempty"`
+	NoPersist   bool        `yaml:"nopersist,omitempty" json:"nopersist,omitempty"`
+	DBAutoID    bool        `yaml:"dbautoid,omitempty" json:"dbautoid,omitempty"`
+	XMLAttrib   string      `yaml:"xmlattrib,omitempty" json:"xmlattrib,omitempty"`
+	PrimaryKey  bool        `yaml:"primarykey,omitempty" json:"primarykey,omitempty"`
+	Nullable    bool        `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Required    bool        `yaml:"required,omitempty" json:"required,omitempty"`
+	Min         modelValue  `yaml:"min,omitempty" json:"min,omitempty"`
+	Max         modelValue  `yaml:"max,omitempty" json:"max,omitempty"`
+	MinLen      int         `yaml:"minlen,omitempty" json:"minlen,omitempty"`
+	MaxLen      int         `yaml:"maxlen,omitempty" json:"maxlen,omitempty"`
+	Pattern     string      `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	References  string      `yaml:"references,omitempty" json:"references,omitempty"`
+	OnDelete    string      `yaml:"ondelete,omitempty" json:"ondelete,omitempty"`
+	Relation    string      `yaml:"relation,omitempty" json:"relation,omitempty"`
+	MappedBy    string      `yaml:"mappedby,omitempty" json:"mappedby,omitempty"`
+
+	line int
+}
+
+type modelEnumValue struct {
+	Name        string `yaml:"name" json:"name"`
+	Value       int    `yaml:"value" json:"value"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	line int
+}
+
+type modelIndex struct {
+	Name        string `yaml:"name,omitempty" json:"name,omitempty"`
+	Fields      string `yaml:"fields" json:"fields"`
+	Prefix      int    `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	FromVersion int    `yaml:"fromversion,omitempty" json:"fromversion,omitempty"`
+
+	line int
+}
+
+// modelValue is a value which is a string in the XML document but may be written as a number, like 'min: 0'
+type modelValue string
+
+func (value *modelValue) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	*value = modelValue(s)
+	return nil
+}
+
+func (value modelValue) MarshalYAML() (interface{}, error) {
+	if _, err := strconv.ParseFloat(string(value), 64); err == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: string(value)}, nil
+	}
+	return string(value), nil
+}
+
+func (value *modelValue) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*value = modelValue(number.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*value = modelValue(s)
+	return nil
+}
+
+func (value modelValue) MarshalJSON() ([]byte, error) {
+	if _, err := strconv.ParseFloat(string(value), 64); err == nil {
+		return []byte(value), nil
+	}
+	return json.Marshal(string(value))
+}
+
+func (imp *modelImport) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		imp.NoPersistence = false
+		return node.Decode(&imp.Package)
+	}
+	type plainImport modelImport
+	return decodeKnownFields(node, (*plainImport)(imp))
+}
+
+func (imp modelImport) MarshalYAML() (interface{}, error) {
+	if !imp.NoPersistence {
+		return imp.Package, nil
+	}
+	type plainImport modelImport
+	return plainImport(imp), nil
+}
+
+func (imp *modelImport) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &imp.Package); err == nil {
+		return nil
+	}
+	type plainImport modelImport
+	return json.Unmarshal(data, (*plainImport)(imp))
+}
+
+func (imp modelImport) MarshalJSON() ([]byte, error) {
+	if !imp.NoPersistence {
+		return json.Marshal(imp.Package)
+	}
+	type plainImport modelImport
+	return json.Marshal(plainImport(imp))
+}
+
+func (include *modelInclude) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		include.Mode = ""
+		return node.Decode(&include.File)
+	}
+	type plainInclude modelInclude
+	return decodeKnownFields(node, (*plainInclude)(include))
+}
+
+func (include modelInclude) MarshalYAML() (interface{}, error) {
+	if include.Mode == "" {
+		return include.File, nil
+	}
+	type plainInclude modelInclude
+	return plainInclude(include), nil
+}
+
+func (include *modelInclude) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &include.File); err == nil {
+		return nil
+	}
+	type plainInclude modelInclude
+	return json.Unmarshal(data, (*plainInclude)(include))
+}
+
+func (include modelInclude) MarshalJSON() ([]byte, error) {
+	if include.Mode == "" {
+		return json.Marshal(include.File)
+	}
+	type plainInclude modelInclude
+	return json.Marshal(plainInclude(include))
+}
+
+// The UnmarshalYAML functions below capture the line for diagnostics, JSON has no positions
+func (define *modelDefine) UnmarshalYAML(node *yaml.Node) error {
+	type plainDefine modelDefine
+	define.line = node.Line
+	return decodeKnownFields(node, (*plainDefine)(define))
+}
+
+func (field *modelField) UnmarshalYAML(node *yaml.Node) error {
+	type plainField modelField
+	field.line = node.Line
+	return decodeKnownFields(node, (*plainField)(field))
+}
+
+func (value *modelEnumValue) UnmarshalYAML(node *yaml.Node) error {
+	type plainEnumValue modelEnumValue
+	value.line = node.Line
+	return decodeKnownFields(node, (*plainEnumValue)(value))
+}
+
+func (index *modelIndex) UnmarshalYAML(node *yaml.Node) error {
+	type plainIndex modelIndex
+	index.line = node.Line
+	return decodeKnownFields(node, (*plainIndex)(index))
+}
+
+func (mapping *modelTypeMapping) UnmarshalYAML(node *yaml.Node) error {
+	type plainTypeMapping modelTypeMapping
+	mapping.line = node.Line
+	return decodeKnownFields(node, (*plainTypeMapping)(mapping))
+}
+
+// decodeKnownFields decodes a mapping and fails on unknown keys, Node.Decode doesn't inherit KnownFields of the decoder
+func decodeKnownFields(node *yaml.Node, out interface{}) error {
+	if node.Kind == yaml.MappingNode {
+		known := map[string]bool{}
+		structType := reflect.TypeOf(out).Elem()
+		for i := 0; i < structType.NumField(); i++ {
+			if key := strings.Split(structType.Field(i).Tag.Get("yaml"), ",")[0]; key != "" && key != "-" {
+				known[key] = true
+			}
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i]; !known[key.Value] {
+				return fmt.Errorf("line %d: unknown key '%s'", key.Line, key.Value)
+			}
+		}
+	}
+	return node.Decode(out)
+}
+
+// splitFieldType splits a field type like '[]*Address' into the type and the list/pointer flags
+func splitFieldType(fieldType string) (string, bool, bool) {
+	isList, isPointer := false, false
+	if strings.HasPrefix(fieldType, "[]") {
+		isList = true
+		fieldType = fieldType[2:]
+	}
+	if strings.HasPrefix(fieldType, "*") {
+		isPointer = true
+		fieldType = fieldType[1:]
+	}
+	return fieldType, isList, isPointer
+}
+
+// joinFieldType is the reverse of splitFieldType
+func joinFieldType(field *XMLDataTypeField) string {
+	fieldType := field.Type
+	if field.IsPointer {
+		fieldType = "*" + fieldType
+	}
+	if field.IsList {
+		fieldType = "[]" + fieldType
+	}
+	return fieldType
+}
+
+func (file *modelFile) toXMLDoc() XMLDoc {
+	doc := XMLDoc{
+		Namespace:       file.Namespace,
+		DBSchema:        file.DBSchema,
+		DBTypeMappings:  toXMLTypeMappings(file.DBTypeMappings),
+		GOTypeMappings:  toXMLTypeMappings(file.GOTypeMappings),
+		AnyTypeMappings: toXMLTypeMappings(file.AnyTypeMappings),
+	}
+	for _, include := range file.Include {
+		doc.Includes = append(doc.Includes, XMLInclude{Filename: include.File, Mode: include.Mode})
+	}
+	for _, imp := range file.Imports {
+		doc.Imports = append(doc.Imports, XMLImport{Package: imp.Package, DisablePersistence: imp.NoPersistence})
+	}
+	if file.DBControl != nil {
+		doc.DBControl = XMLDBControl(*file.DBControl)
+	}
+	for _, define := range file.Defines {
+		doc.Defines = append(doc.Defines, define.toXMLDefine())
+	}
+	return doc
+}
+
+func toXMLTypeMappings(mappings []modelTypeMapping) []XMLTypeMapping {
+	var result []XMLTypeMapping
+	for _, mapping := range mappings {
+		result = append(result, XMLTypeMapping{
+			Lang:      mapping.Lang,
+			FromType:  mapping.From,
+			ToType:    mapping.To,
+			Encode:    mapping.Encode,
+			Decode:    mapping.Decode,
+			FieldSize: mapping.FieldSize,
+			Pos:       SourcePos{Line: mapping.line},
+		})
+	}
+	return result
+}
+
+func (define *modelDefine) toXMLDefine() XMLDefine {
+	result := XMLDefine{
+		Type:            define.Type,
+		Name:            define.Name,
+		Inherits:        define.Inherits,
+		Inheritance:     define.Inheritance,
+		DBSchema:        define.DBSchema,
+		Prefix:          define.Prefix,
+		SkipPersistance: define.NoPersist,
+		Override:        define.Override,
+		FromVersion:     define.FromVersion,
+		ToVersion:       define.ToVersion,
+		Removed:         define.Removed,
+		Description:     define.Description,
+		Pos:             SourcePos{Line: define.line},
+	}
+	for _, field := range define.Fields {
+		fieldType, isList, isPointer := splitFieldType(field.Type)
+		result.Fields = append(result.Fields, XMLDataTypeField{
+			Name:            field.Name,
+			Type:            fieldType,
+			IsList:          isList || field.IsList,
+			IsPointer:       isPointer || field.IsPointer,
+			Default:         (*string)(field.Default),
+			DBSize:          field.DBSize,
+			FieldSize:       field.FieldSize,
+			FromVersion:     field.FromVersion,
+			ToVersion:       field.ToVersion,
+			Removed:         field.Removed,
+			SkipPersistance: field.NoPersist,
+			DBAutoID:        field.DBAutoID,
+			XMLAttrib:       field.XMLAttrib,
+			PrimaryKey:      field.PrimaryKey,
+			Nullable:        field.Nullable,
+			Required:        field.Required,
+			Min:             string(field.Min),
+			Max:             string(field.Max),
+			MinLen:          field.MinLen,
+			MaxLen:          field.MaxLen,
+			Pattern:         field.Pattern,
+			References:      field.References,
+			OnDelete:        field.OnDelete,
+			Relation:        field.Relation,
+			MappedBy:        field.MappedBy,
+			Description:     field.Description,
+			Pos:             SourcePos{Line: field.line},
+		})
+	}
+	for _, value := range define.Values {
+		result.Ints = append(result.Ints, XMLDataTypeField{Name: value.Name, Value: value.Value, Description: value.Description, Pos: SourcePos{Line: value.line}})
+	}
+	for _, index := range define.Indexes {
+		result.Indexes = append(result.Indexes, index.toXMLIndex())
+	}
+	for _, index := range define.Uniques {
+		result.Uniques = append(result.Uniques, index.toXMLIndex())
+	}
+	return result
+}
+
+func (index *modelIndex) toXMLIndex() XMLIndex {
+	return XMLIndex{Name: index.Name, Fields: index.Fields, Prefix: index.Prefix, FromVersion: index.FromVersion, Pos: SourcePos{Line: index.line}}
+}
+
+func fromXMLDoc(doc *XMLDoc) modelFile {
+	file := modelFile{
+		Namespace:       doc.Namespace,
+		DBSchema:        doc.DBSchema,
+		DBTypeMappings:  fromXMLTypeMappings(doc.DBTypeMappings),
+		GOTypeMappings:  fromXMLTypeMappings(doc.GOTypeMappings),
+		AnyTypeMappings: fromXMLTypeMappings(doc.AnyTypeMappings),
+	}
+	for _, include := range doc.Includes {
+		file.Include = append(file.Include, modelInclude{File: strings.TrimSpace(include.Filename), Mode: include.Mode})
+	}
+	for _, imp := range doc.Imports {
+		file.Imports = append(file.Imports, modelImport{Package: strings.TrimSpace(imp.Package), NoPersistence: imp.DisablePersistence})
+	}
+	if doc.DBControl != (XMLDBControl{}) {
+		control := modelDBControl(doc.DBControl)
+		file.DBControl = &control
+	}
+	for i := range doc.Defines {
+		file.Defines = append(file.Defines, fromXMLDefine(&doc.Defines[i]))
+	}
+	return file
+}
+
+func fromXMLTypeMappings(mappings []XMLTypeMapping) []modelTypeMapping {
+	var result []modelTypeMapping
+	for _, mapping := range mappings {
+		result = append(result, modelTypeMapping{
+			Lang:      mapping.Lang,
+			From:      mapping.FromType,
+			To:        mapping.ToType,
+			Encode:    mapping.Encode,
+			Decode:    mapping.Decode,
+			FieldSize: mapping.FieldSize,
+		})
+	}
+	return result
+}
+
+func fromXMLDefine(define *XMLDefine) modelDefine {
+	result := modelDefine{
+		Type:        define.Type,
+		Name:        define.Name,
+		Inherits:    define.Inherits,
+		Inheritance: define.Inheritance,
+		DBSchema:    define.DBSchema,
+		Prefix:      define.Prefix,
+		NoPersist:   define.SkipPersistance,
+		Override:    define.Override,
+		FromVersion: define.FromVersion,
+		ToVersion:   define.ToVersion,
+		Removed:     define.Removed,
+		Description: strings.Join(define.Documentation(), "\n"),
+	}
+	for i := range define.Fields {
+		field := &define.Fields[i]
+		result.Fields = append(result.Fields, modelField{
+			Name:        field.Name,
+			Type:        joinFieldType(field),
+			Default:     (*modelValue)(field.Default),
+			DBSize:      field.DBSize,
+			FieldSize:   field.FieldSize,
+			FromVersion: field.FromVersion,
+			ToVersion:   field.ToVersion,
+			Removed:     field.Removed,
+			NoPersist:   field.SkipPersistance,
+			DBAutoID:    field.DBAutoID,
+			XMLAttrib:   field.XMLAttrib,
+			PrimaryKey:  field.PrimaryKey,
+			Nullable:    field.Nullable,
+			Required:    field.Required,
+			Min:         modelValue(field.Min),
+			Max:         modelValue(field.Max),
+			MinLen:      field.MinLen,
+			MaxLen:      field.MaxLen,
+			Pattern:     field.Pattern,
+			References:  field.References,
+			OnDelete:    field.OnDelete,
+			Relation:    field.Relation,
+			MappedBy:    field.MappedBy,
+			Description: strings.Join(field.Documentation(), "\n"),
+		})
+	}
+	for _, value := range define.Ints {
+		result.Values = append(result.Values, modelEnumValue{Name: value.Name, Value: value.Value, Description: strings.Join(value.Documentation(), "\n")})
+	}
+	for _, index := range define.Indexes {
+		result.Indexes = append(result.Indexes, modelIndex{Name: index.Name, Fields: index.Fields, Prefix: index.Prefix, FromVersion: index.FromVersion})
+	}
+	for _, index := range define.Uniques {
+		result.Uniques = append(result.Uniques, modelIndex{Name: index.Name, Fields: index.Fields, Prefix: index.Prefix, FromVersion: index.FromVersion})
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// SourcePos records where in a model file an element was declared
+type SourcePos struct {
+	File string
+	Line int
+}
+
+func (pos SourcePos) String() string {
+	if pos.Line == 0 {
+		return pos.File
+	}
+	return fmt.Sprintf("%s:%d", pos.File, pos.Line)
+}
+
+// The UnmarshalXML functions below only capture the line of the start element
+// and then let encoding/xml do the actual decoding through an alias type
+func (define *XMLDefine) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type xmlDefine XMLDefine
+	line, _ := d.InputPos()
+	if err := d.DecodeElement((*xmlDefine)(define), &start); err != nil {
+		return err
+	}
+	define.Pos.Line = line
+	return nil
+}
+
+func (field *XMLDataTypeField) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type xmlDataTypeField XMLDataTypeField
+	line, _ := d.InputPos()
+	if err := d.DecodeElement((*xmlDataTypeField)(field), &start); err != nil {
+		return err
+	}
+	field.Pos.Line = line
+	return nil
+}
+
+func (mapping *XMLTypeMapping) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type xmlTypeMapping XMLTypeMapping
+	line, _ := d.InputPos()
+	if err := d.DecodeElement((*xmlTypeMapping)(mapping), &start); err != nil {
+		return err
+	}
+	mapping.Pos.Line = line
+	return nil
+}
+
+func (index *XMLIndex) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type xmlIndex XMLIndex
+	line, _ := d.InputPos()
+	if err := d.DecodeElement((*xmlIndex)(index), &start); err != nil {
+		return err
+	}
+	index.Pos.Line = line
+	return nil
+}
+
+// SetSourceFile stamps the file name on all positioned elements of the document, call this
+// before the document is merged with any includes
+func (doc *XMLDoc) SetSourceFile(filename string) {
+	doc.Filename = filename
+	for i := range doc.Defines {
+		define := &doc.Defines[i]
+		define.Pos.File = filename
+		for _, fields := range [][]XMLDataTypeField{define.Fields, define.Ints} {
+			for j := range fields {
+				fields[j].Pos.File = filename
+			}
+		}
+		for _, indexes := range [][]XMLIndex{define.Indexes, define.Uniques} {
+			for j := range indexes {
+				indexes[j].Pos.File = filename
+			}
+		}
+	}
+	for _, mappings := range [][]XMLTypeMapping{doc.DBTypeMappings, doc.GOTypeMappings, doc.AnyTypeMappings} {
+		for i := range mappings {
+			mappings[i].Pos.File = filename
+		}
+	}
+}
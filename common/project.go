@@ -0,0 +1,205 @@
+package common
+
+//
+// Project file (modelgen.yaml), holds the generator options so they don't have to be given on the command line
+//
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the project file used when neither a project file nor a model is given on the command line
+const ProjectFileName = "modelgen.yaml"
+
+// ProjectClassList is a list of class names, written as a YAML list or as a comma separated string ('-' for all classes)
+type ProjectClassList []string
+
+func (list *ProjectClassList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		*list = nil
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				*list = append(*list, name)
+			}
+		}
+		return nil
+	}
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		return err
+	}
+	*list = names
+	return nil
+}
+
+//
+// Settings of a project or a target, settings which are not given keep their default (pointers are nil)
+//
+type ProjectSettings struct {
+	Model             string            `yaml:"model"`       // model file, relative to the project file
+	Language          string            `yaml:"language"`    // go, cpp or ts (-l)
+	Output            string            `yaml:"output"`      // model output file or '-' for stdout (-o)
+	DBOutput          string            `yaml:"dboutput"`    // persistence output file (-O)
+	SQLOutput         string            `yaml:"sqloutput"`   // DB create script, stdout when not given
+	TablePrefix       *string           `yaml:"tableprefix"` // (-P)
+	Persistence       ProjectClassList  `yaml:"persistence"` // classes to generate persistence for, '-' for all (-p)
+	GettersAndSetters *bool             `yaml:"getterssetters"`
+	Converters        *bool             `yaml:"converters"`
+	MemberPrefix      *string           `yaml:"memberprefix"` // (-m)
+	SplitInFiles      *bool             `yaml:"split"`
+	Marshalling       *bool             `yaml:"marshalling"` // (-M)
+	Validation        *bool             `yaml:"validation"`  // (-V)
+	ValidateOnPersist *bool             `yaml:"validateonpersist"`
+	DropStatements    *bool             `yaml:"drop"`            // (-d)
+	FromVersion       *int              `yaml:"fromversion"`     // (-f)
+	GoModulePath      *string           `yaml:"gomodule"`        // (-G)
+	LanguageOptions   map[string]string `yaml:"languageoptions"` // options of the language (-X)
+	Templates         string            `yaml:"templates"`       // template directory, relative to the project file (-T)
+}
+
+// ProjectTarget is one generation run of a project, its settings override the project wide settings
+type ProjectTarget struct {
+	Name            string `yaml:"name"`
+	ProjectSettings `yaml:",inline"`
+}
+
+//
+// Project as read from the project file
+//
+type Project struct {
+	ProjectSettings `yaml:",inline"`
+	Targets         []ProjectTarget `yaml:"targets"`
+	Filename        string          `yaml:"-"`
+}
+
+//
+// Reads a project file, unknown keys are an error
+//
+func LoadProject(filename string) (*Project, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	project := Project{Filename: filename}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&project); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if len(project.Targets) == 0 {
+		project.Targets = []ProjectTarget{{Name: project.Language}}
+	} else if len(project.LanguageOptions) > 0 {
+		// The options are declared by a language, a go 'package' is unknown to the cpp target
+		return nil, fmt.Errorf("%s: 'languageoptions' are given per target, the options of a language don't apply to the other targets", filename)
+	}
+	for i := range project.Targets {
+		target := &project.Targets[i]
+		if target.Name == "" {
+			target.Name = target.Language
+		}
+		if target.Model == "" && project.Model == "" {
+			return nil, fmt.Errorf("%s: no model given for target '%s'", filename, target.Name)
+		}
+	}
+	return &project, nil
+}
+
+//
+// Applies the project wide settings and then the settings of the target to the options
+//
+func (project *Project) Apply(target *ProjectTarget, options *Options) {
+	dir := filepath.Dir(project.Filename)
+	project.ProjectSettings.apply(options, dir)
+	target.ProjectSettings.apply(options, dir)
+}
+
+// projectPath makes a path of the project file relative to the project file, '-' (stdout) is kept
+func projectPath(dir string, path string) string {
+	if path == "-" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (settings *ProjectSettings) apply(options *Options, dir string) {
+	if settings.Model != "" {
+		options.Filename = projectPath(dir, settings.Model)
+	}
+	if settings.Language != "" {
+		options.UseLanguage = settings.Language
+	}
+	if settings.Output != "" {
+		options.OutputName = projectPath(dir, settings.Output)
+	}
+	if settings.DBOutput != "" {
+		options.OutputDBName = projectPath(dir, settings.DBOutput)
+	}
+	if settings.SQLOutput != "" {
+		options.OutputSQLName = projectPath(dir, settings.SQLOutput)
+	}
+	if settings.TablePrefix != nil {
+		options.DBTablePrefix = *settings.TablePrefix
+	}
+	if len(settings.Persistence) > 0 {
+		options.DoPersistence = true
+		options.AllPersistenceClasses = settings.Persistence
+		options.PersistenceClass = settings.Persistence[0]
+	}
+	if settings.GettersAndSetters != nil {
+		options.GettersAndSetters = *settings.GettersAndSetters
+	}
+	if settings.Converters != nil {
+		options.Converters = *settings.Converters
+	}
+	if settings.MemberPrefix != nil {
+		options.MemberPrefix = *settings.MemberPrefix
+	}
+	if settings.SplitInFiles != nil {
+		options.SplitInFiles = *settings.SplitInFiles
+	}
+	if settings.Marshalling != nil {
+		options.CPPJson = *settings.Marshalling
+	}
+	if settings.Validation != nil {
+		options.GenerateValidation = *settings.Validation
+	}
+	if settings.ValidateOnPersist != nil {
+		options.ValidateOnPersist = *settings.ValidateOnPersist
+		// Validate() has to be generated to be called
+		if options.ValidateOnPersist {
+			options.GenerateValidation = true
+		}
+	}
+	if settings.DropStatements != nil {
+		options.GenerateDropStatement = *settings.DropStatements
+	}
+	if settings.FromVersion != nil {
+		options.FromVersion = *settings.FromVersion
+		options.IsUpgrade = options.FromVersion > 0
+	}
+	if settings.GoModulePath != nil {
+		options.GoModulePath = *settings.GoModulePath
+	}
+	if settings.Templates != "" {
+		options.TemplateDirectory = projectPath(dir, settings.Templates)
+	}
+	names := make([]string, 0, len(settings.LanguageOptions))
+	for name := range settings.LanguageOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		options.SetLanguageOption(name, settings.LanguageOptions[name])
+	}
+}
@@ -0,0 +1,48 @@
+package common
+
+//
+// Referenced documents (include mode="reference"). The types of a referenced document are used by name but belong to
+// the namespace of the referenced document, they are generated from that document and imported by the generated code.
+//
+
+import (
+	"path"
+	"strings"
+)
+
+// NamespaceParts splits a namespace like 'shared.common' into its parts
+func NamespaceParts(namespace string) []string {
+	return strings.Split(namespace, ".")
+}
+
+// PackageName returns the Go package name of the document, the last part of the namespace
+func (doc *XMLDoc) PackageName() string {
+	parts := NamespaceParts(doc.Namespace)
+	return parts[len(parts)-1]
+}
+
+// GoImportPath returns the Go import path of the document, the namespace parts below the module path
+func (doc *XMLDoc) GoImportPath(modulePath string) string {
+	return path.Join(append([]string{modulePath}, NamespaceParts(doc.Namespace)...)...)
+}
+
+// CppNamespace returns the C++ namespace of the document, 'shared.common' is the nested namespace 'shared::common'
+func (doc *XMLDoc) CppNamespace() string {
+	return strings.Join(NamespaceParts(doc.Namespace), "::")
+}
+
+// ModulePath returns the path of the generated file for the document relative to the other namespaces, 'shared/common'
+func (doc *XMLDoc) ModulePath() string {
+	return strings.Join(NamespaceParts(doc.Namespace), "/")
+}
+
+// FindReferencedDefines returns the referenced documents declaring a define with the given name, more than one is ambiguous
+func (doc *XMLDoc) FindReferencedDefines(name string) []*XMLDoc {
+	docs := []*XMLDoc{}
+	for _, refDoc := range doc.References {
+		if refDoc.FindDefine(name) != nil {
+			docs = append(docs, refDoc)
+		}
+	}
+	return docs
+}
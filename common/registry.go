@@ -0,0 +1,161 @@
+package common
+
+//
+// Registry of the target languages, a language package registers itself from its init function
+// and is compiled in with a blank import (see languages.go in the main package)
+//
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LanguageOption is an option of a language, given with '-X name=value' or in the 'languageoptions' of a project target
+type LanguageOption struct {
+	Name string
+	Arg  string // name of the value, a switch without a value is set to "true"
+	Help string
+	// Apply sets the option, when nil the value is stored in Options.LanguageOptions (see LanguageOption)
+	Apply func(options *Options, value string) error
+}
+
+// LanguageInfo describes a registered language
+type LanguageInfo struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Create      func() Language
+	Options     []LanguageOption
+}
+
+var languages = []*LanguageInfo{}
+
+//
+// Registers a language, the name and aliases must be unique (case insensitive)
+//
+func RegisterLanguage(info LanguageInfo) {
+	for _, name := range info.Names() {
+		if other := FindLanguage(name); other != nil {
+			log.Panicf("Language '%s' already registered by '%s'", name, other.Name)
+		}
+	}
+	languages = append(languages, &info)
+}
+
+// FindLanguage returns the language registered with the name or alias, nil if there is none
+func FindLanguage(name string) *LanguageInfo {
+	for _, info := range languages {
+		for _, languageName := range info.Names() {
+			if strings.EqualFold(languageName, name) {
+				return info
+			}
+		}
+	}
+	return nil
+}
+
+// Languages returns the registered languages sorted by name
+func Languages() []*LanguageInfo {
+	sorted := append([]*LanguageInfo{}, languages...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// LanguageNames returns the names of the registered languages, for messages
+func LanguageNames() string {
+	names := []string{}
+	for _, info := range Languages() {
+		names = append(names, info.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Names returns the name and the aliases of the language
+func (info *LanguageInfo) Names() []string {
+	return append([]string{info.Name}, info.Aliases...)
+}
+
+// Capabilities returns what the language generates: the domain model, the persistence (CRUD) and the DB create script
+func (info *LanguageInfo) Capabilities() []string {
+	language := info.Create()
+	capabilities := []string{}
+	if language.GetModelGenerator() != nil {
+		capabilities = append(capabilities, "model")
+	}
+	if language.GetCrudGenerator() != nil {
+		capabilities = append(capabilities, "crud")
+	}
+	if language.GetDBCreateGenerator() != nil {
+		capabilities = append(capabilities, "dbcreate")
+	}
+	return capabilities
+}
+
+func (info *LanguageInfo) findOption(name string) *LanguageOption {
+	for i := range info.Options {
+		if info.Options[i].Name == name {
+			return &info.Options[i]
+		}
+	}
+	return nil
+}
+
+//
+// Checks the language options given and applies the ones with an Apply function
+//
+func (info *LanguageInfo) ApplyOptions(options *Options) error {
+	names := make([]string, 0, len(options.LanguageOptions))
+	for name := range options.LanguageOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		option := info.findOption(name)
+		if option == nil {
+			known := []string{}
+			for _, option := range info.Options {
+				known = append(known, option.Name)
+			}
+			if len(known) == 0 {
+				return fmt.Errorf("language %s has no options, unknown option '%s'", info.Name, name)
+			}
+			return fmt.Errorf("unknown option '%s' for language %s (options: %s)", name, info.Name, strings.Join(known, ", "))
+		}
+		if option.Apply != nil {
+			if err := option.Apply(options, options.LanguageOptions[name]); err != nil {
+				return fmt.Errorf("option '%s' for language %s: %s", name, info.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SetLanguageOption sets a language option, the map is copied so copies of the options are not changed
+func (options *Options) SetLanguageOption(name string, value string) {
+	values := map[string]string{}
+	for key, value := range options.LanguageOptions {
+		values[key] = value
+	}
+	values[name] = value
+	options.LanguageOptions = values
+}
+
+// LanguageOption returns the value of a language option, empty if not given
+func (options *Options) LanguageOption(name string) string {
+	return options.LanguageOptions[name]
+}
+
+// BoolOption is the Apply function of a switch, the value is parsed with strconv.ParseBool
+func BoolOption(set func(options *Options, value bool)) func(options *Options, value string) error {
+	return func(options *Options, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s', expected true or false", value)
+		}
+		set(options, b)
+		return nil
+	}
+}
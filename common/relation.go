@@ -0,0 +1,238 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relation kinds for list fields ('relation' attribute)
+const (
+	RelationOneToMany  = "onetomany"
+	RelationManyToMany = "manytomany"
+)
+
+// On delete actions for references ('ondelete' attribute) and the SQL they translate to
+var onDeleteActions = map[string]string{
+	"cascade":  "CASCADE",
+	"restrict": "RESTRICT",
+	"setnull":  "SET NULL",
+	"noaction": "NO ACTION",
+}
+
+// FindDefine returns the define with the given name or nil if there is none
+func (doc *XMLDoc) FindDefine(name string) *XMLDefine {
+	for i := range doc.Defines {
+		if doc.Defines[i].Name == name {
+			return &doc.Defines[i]
+		}
+	}
+	return nil
+}
+
+// FindField returns the field with the given name or nil if there is none
+func (define *XMLDefine) FindField(name string) *XMLDataTypeField {
+	for i := range define.Fields {
+		if define.Fields[i].Name == name {
+			return &define.Fields[i]
+		}
+	}
+	return nil
+}
+
+// IsPersisted returns true if the field is stored as a column in the table of the class.
+// Relation fields are navigation only, they are resolved through the other side or a join table. Removed fields are dropped.
+func (field *XMLDataTypeField) IsPersisted() bool {
+	return !field.SkipPersistance && field.Relation == "" && !field.IsRemoved()
+}
+
+// OnDeleteAction returns the SQL for the 'ondelete' attribute, empty if not specified
+func (field *XMLDataTypeField) OnDeleteAction() (string, error) {
+	if field.OnDelete == "" {
+		return "", nil
+	}
+	action, ok := onDeleteActions[strings.ToLower(strings.Replace(field.OnDelete, " ", "", -1))]
+	if !ok {
+		return "", fmt.Errorf("unknown ondelete action '%s' (cascade, restrict, setnull or noaction)", field.OnDelete)
+	}
+	return action, nil
+}
+
+// ResolveReference resolves the 'references' attribute of a field, "Class.Field" or just "Class" which
+// references the primary key of the class (the class must have a single field key in that case)
+func (doc *XMLDoc) ResolveReference(field *XMLDataTypeField) (*XMLDefine, *XMLDataTypeField, error) {
+	className, fieldName := field.References, ""
+	if idx := strings.Index(field.References, "."); idx >= 0 {
+		className, fieldName = field.References[:idx], field.References[idx+1:]
+	}
+	define := doc.FindDefine(className)
+	if define == nil || define.Type != "class" {
+		return nil, nil, fmt.Errorf("references unknown class '%s'", className)
+	}
+	if fieldName == "" {
+		return doc.singleKey(define)
+	}
+	refField := doc.FindClassField(define, fieldName)
+	if refField == nil {
+		return nil, nil, fmt.Errorf("references unknown field '%s.%s'", className, fieldName)
+	}
+	return define, refField, nil
+}
+
+// singleKey returns the primary key of a class which must consist of a single field
+func (doc *XMLDoc) singleKey(define *XMLDefine) (*XMLDefine, *XMLDataTypeField, error) {
+	keys := doc.ClassKeys(define)
+	if len(keys) != 1 {
+		return nil, nil, fmt.Errorf("class '%s' must have a single field primary key", define.Name)
+	}
+	return define, keys[0], nil
+}
+
+// ResolveOneToMany resolves a 'onetomany' list field in the parent class to the field in the child class
+// referencing the parent. The child field is given by 'mappedby' or is the only field in the child referencing the parent.
+func (doc *XMLDoc) ResolveOneToMany(parent *XMLDefine, field *XMLDataTypeField) (*XMLDefine, *XMLDataTypeField, error) {
+	child := doc.FindDefine(field.Type)
+	if child == nil || child.Type != "class" {
+		return nil, nil, fmt.Errorf("relation on '%s::%s' requires a class type, got '%s'", parent.Name, field.Name, field.Type)
+	}
+	var childField *XMLDataTypeField
+	for i := range child.Fields {
+		candidate := &child.Fields[i]
+		if candidate.References == "" || candidate.IsRemoved() {
+			continue
+		}
+		if field.MappedBy != "" && candidate.Name != field.MappedBy {
+			continue
+		}
+		refDefine, _, err := doc.ResolveReference(candidate)
+		if err != nil || refDefine.Name != parent.Name {
+			continue
+		}
+		if childField != nil {
+			return nil, nil, fmt.Errorf("'%s' has several fields referencing '%s', use 'mappedby' on '%s::%s'", child.Name, parent.Name, parent.Name, field.Name)
+		}
+		childField = candidate
+	}
+	if childField == nil {
+		return nil, nil, fmt.Errorf("no field in '%s' references '%s' (required by '%s::%s')", child.Name, parent.Name, parent.Name, field.Name)
+	}
+	return child, childField, nil
+}
+
+// ResolveManyToMany resolves a 'manytomany' list field to the keys used by the join table,
+// both classes must have a single field primary key
+func (doc *XMLDoc) ResolveManyToMany(parent *XMLDefine, field *XMLDataTypeField) (parentKey *XMLDataTypeField, child *XMLDefine, childKey *XMLDataTypeField, err error) {
+	child = doc.FindDefine(field.Type)
+	if child == nil || child.Type != "class" {
+		return nil, nil, nil, fmt.Errorf("relation on '%s::%s' requires a class type, got '%s'", parent.Name, field.Name, field.Type)
+	}
+	if _, parentKey, err = doc.singleKey(parent); err != nil {
+		return nil, nil, nil, err
+	}
+	if _, childKey, err = doc.singleKey(child); err != nil {
+		return nil, nil, nil, err
+	}
+	return parentKey, child, childKey, nil
+}
+
+// JoinColumnNames returns the column names of the join table for a many to many relation
+func JoinColumnNames(parentKey *XMLDataTypeField, field *XMLDataTypeField, childKey *XMLDataTypeField) (string, string) {
+	parentColumn := strings.ToLower(parentKey.Name)
+	childColumn := strings.ToLower(childKey.Name)
+	if parentColumn == childColumn {
+		// Self referencing (like User.Friends), qualify the child side with the field name
+		childColumn = strings.ToLower(field.Name) + "_" + childColumn
+	}
+	return parentColumn, childColumn
+}
+
+// ClassDependencies returns the names of the classes a class depends on through references, including the references
+// of inherited fields (self references excluded). Joined inheritance in the chain adds a dependency on the parent table.
+func (doc *XMLDoc) ClassDependencies(define *XMLDefine) []string {
+	deps := []string{}
+	for _, current := range doc.InheritanceChain(define) {
+		if current.IsJoined() && current.Inherits != define.Name {
+			deps = append(deps, current.Inherits)
+		}
+		for i := range current.Fields {
+			field := &current.Fields[i]
+			if field.References == "" || !field.IsPersisted() {
+				continue
+			}
+			refDefine, _, err := doc.ResolveReference(field)
+			if err != nil || refDefine.Name == define.Name {
+				continue
+			}
+			deps = append(deps, refDefine.Name)
+		}
+	}
+	return deps
+}
+
+// SortDefinesByDependency orders the defines so a referenced class comes before the classes referencing it,
+// otherwise the declaration order is kept. Classes in a reference cycle are returned in declaration order and reported as an error.
+func (doc *XMLDoc) SortDefinesByDependency() ([]*XMLDefine, error) {
+	sorted := []*XMLDefine{}
+	done := make(map[string]bool)
+	remaining := []*XMLDefine{}
+	for i := range doc.Defines {
+		remaining = append(remaining, &doc.Defines[i])
+	}
+
+	for len(remaining) > 0 {
+		next := []*XMLDefine{}
+		for _, define := range remaining {
+			ready := true
+			for _, dep := range doc.ClassDependencies(define) {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, define)
+				done[define.Name] = true
+			} else {
+				next = append(next, define)
+			}
+		}
+		if len(next) == len(remaining) {
+			names := []string{}
+			for _, define := range next {
+				names = append(names, define.Name)
+				sorted = append(sorted, define)
+			}
+			return sorted, fmt.Errorf("reference cycle between classes: %s", strings.Join(names, ", "))
+		}
+		remaining = next
+	}
+	return sorted, nil
+}
+
+// DependencyLevels returns the creation level of every class, 1 for a class without references and one more than the highest
+// level of the classes it references otherwise. Classes of the same level don't depend on each other, adding a class
+// changes only the levels of the classes referencing it. A reference closing a cycle is ignored.
+func (doc *XMLDoc) DependencyLevels() map[string]int {
+	levels := make(map[string]int)
+	var level func(define *XMLDefine) int
+	level = func(define *XMLDefine) int {
+		if current, ok := levels[define.Name]; ok {
+			// 0 while the level of the class is computed, a reference cycle
+			return current
+		}
+		levels[define.Name] = 0
+		result := 1
+		for _, dep := range doc.ClassDependencies(define) {
+			if depDefine := doc.FindDefine(dep); depDefine != nil {
+				if depLevel := level(depDefine) + 1; depLevel > result {
+					result = depLevel
+				}
+			}
+		}
+		levels[define.Name] = result
+		return result
+	}
+	for i := range doc.Defines {
+		level(&doc.Defines[i])
+	}
+	return levels
+}
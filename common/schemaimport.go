@@ -0,0 +1,440 @@
+package common
+
+//
+// Import of JSON Schema documents and the components/schemas section of OpenAPI 3 files (JSON or YAML)
+// Only local references ('#/...') are supported
+//
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchema holds the parts of a (JSON Schema / OpenAPI) schema the importer understands, everything else is ignored
+type jsonSchema struct {
+	Ref         string        `yaml:"$ref"`
+	Type        schemaType    `yaml:"type"`
+	Format      string        `yaml:"format"`
+	Title       string        `yaml:"title"`
+	Description string        `yaml:"description"`
+	Properties  schemaMap     `yaml:"properties"`
+	Required    []string      `yaml:"required"`
+	Items       *jsonSchema   `yaml:"items"`
+	Enum        []interface{} `yaml:"enum"`
+	EnumNames   []string      `yaml:"x-enum-varnames"`
+	Nullable    bool          `yaml:"nullable"`
+	AllOf       []*jsonSchema `yaml:"allOf"`
+	OneOf       []*jsonSchema `yaml:"oneOf"`
+	AnyOf       []*jsonSchema `yaml:"anyOf"`
+	Default     yaml.Node     `yaml:"default"`
+	Minimum     yaml.Node     `yaml:"minimum"`
+	Maximum     yaml.Node     `yaml:"maximum"`
+	MinLength   int           `yaml:"minLength"`
+	MaxLength   int           `yaml:"maxLength"`
+	MinItems    int           `yaml:"minItems"`
+	MaxItems    int           `yaml:"maxItems"`
+	Pattern     string        `yaml:"pattern"`
+	Definitions schemaMap     `yaml:"definitions"`
+	Defs        schemaMap     `yaml:"$defs"`
+
+	line int
+}
+
+func (schema *jsonSchema) UnmarshalYAML(node *yaml.Node) error {
+	type plainSchema jsonSchema
+	schema.line = node.Line
+	if node.Kind == yaml.ScalarNode {
+		// boolean schema (true/false), anything goes
+		return nil
+	}
+	return node.Decode((*plainSchema)(schema))
+}
+
+// schemaType is the 'type' of a schema, a single type or a list (like [string, "null"])
+type schemaType []string
+
+func (types *schemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*types = schemaType{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(types))
+}
+
+// name returns the type without 'null', empty if not specified
+func (types schemaType) name() string {
+	for _, name := range types {
+		if name != "null" {
+			return name
+		}
+	}
+	return ""
+}
+
+func (types schemaType) nullable() bool {
+	for _, name := range types {
+		if name == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaMap holds named schemas in declaration order
+type schemaMap struct {
+	names   []string
+	schemas map[string]*jsonSchema
+}
+
+func (schemas *schemaMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of schemas", node.Line)
+	}
+	schemas.schemas = make(map[string]*jsonSchema)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		schema := &jsonSchema{}
+		if err := node.Content[i+1].Decode(schema); err != nil {
+			return err
+		}
+		schemas.names = append(schemas.names, name)
+		schemas.schemas[name] = schema
+	}
+	return nil
+}
+
+// openAPIDocument holds the parts of an OpenAPI 3 document with schemas
+type openAPIDocument struct {
+	OpenAPI    string `yaml:"openapi"`
+	Components struct {
+		Schemas schemaMap `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// Model types for the string formats, anything else is a string
+var schemaStringFormats = map[string]string{
+	"uuid":      "guid",
+	"date-time": "time",
+}
+
+// schemaImporter holds the state while importing a schema document
+type schemaImporter struct {
+	filename string
+	options  *Options
+	doc      XMLDoc
+	schemas  map[string]*jsonSchema // all named schemas
+	defined  map[string]bool
+	used     map[string]bool
+}
+
+// ImportSchemaFile reads a JSON Schema or OpenAPI 3 file and creates a model from the schemas, see ImportSchema
+func ImportSchemaFile(filename string, options *Options) (XMLDoc, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+	return ImportSchema(filename, data, options)
+}
+
+// ImportSchema creates classes and enums from the 'components/schemas' of an OpenAPI 3 document or from a JSON Schema
+// (the definitions/$defs and the root schema if it is an object). Objects become classes, enums become enum defines,
+// properties become fields: 'format: uuid' and 'date-time' map to guid and time, '$ref' to the referenced define
+// and arrays to lists. Anything which can't be expressed in the model is reported as a warning.
+func ImportSchema(filename string, data []byte, options *Options) (XMLDoc, error) {
+	// JSON is YAML, so a single parser does
+	var openAPI openAPIDocument
+	if err := yaml.Unmarshal(data, &openAPI); err != nil {
+		return XMLDoc{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	var root jsonSchema
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return XMLDoc{}, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	importer := schemaImporter{
+		filename: filename,
+		options:  options,
+		schemas:  make(map[string]*jsonSchema),
+		defined:  make(map[string]bool),
+		used:     make(map[string]bool),
+	}
+	named := []schemaMap{openAPI.Components.Schemas}
+	if openAPI.OpenAPI == "" {
+		named = append(named, root.Definitions, root.Defs)
+		if root.Type.name() == "object" || len(root.Properties.names) > 0 || len(root.AllOf) > 0 {
+			name := root.Title
+			if name == "" {
+				name = importNamespace(filename)
+			}
+			named = append(named, schemaMap{names: []string{name}, schemas: map[string]*jsonSchema{name: &root}})
+		}
+	}
+	names := []string{}
+	for _, schemas := range named {
+		for _, name := range schemas.names {
+			if _, ok := importer.schemas[name]; ok {
+				log.Printf("!WARNING!: %s:%d: schema '%s' is declared twice, using the first declaration\n", filename, schemas.schemas[name].line, name)
+				continue
+			}
+			importer.schemas[name] = schemas.schemas[name]
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return XMLDoc{}, fmt.Errorf("%s: no schemas found (components/schemas, definitions, $defs or an object schema)", filename)
+	}
+
+	for _, name := range names {
+		importer.define(name, importer.schemas[name])
+	}
+	if len(importer.doc.Defines) == 0 {
+		return XMLDoc{}, fmt.Errorf("%s: no object or enum schemas found", filename)
+	}
+	// A recursive '$ref' (like a parent node) is held by pointer, the class can't contain itself by value
+	for i := range importer.doc.Defines {
+		for cycle := importer.doc.valueCycle(&importer.doc.Defines[i]); len(cycle) > 0; cycle = importer.doc.valueCycle(&importer.doc.Defines[i]) {
+			cycle[len(cycle)-1].field.IsPointer = true
+		}
+	}
+	addImportTypeMappings(&importer.doc, importer.used, true)
+
+	doc := importer.doc
+	doc.Namespace = importNamespace(filename)
+	doc.SetSourceFile(filename)
+	return doc, nil
+}
+
+func (importer *schemaImporter) warnf(line int, format string, args ...interface{}) {
+	log.Printf("!WARNING!: %s:%d: %s\n", importer.filename, line, fmt.Sprintf(format, args...))
+}
+
+// refName returns the schema name of a local reference, like '#/components/schemas/Pet'
+func (importer *schemaImporter) refName(schema *jsonSchema) (string, bool) {
+	if !strings.HasPrefix(schema.Ref, "#/") {
+		importer.warnf(schema.line, "reference '%s' is not supported, only local references ('#/...')", schema.Ref)
+		return "", false
+	}
+	name := schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+	if _, ok := importer.schemas[name]; !ok {
+		importer.warnf(schema.line, "reference '%s' to unknown schema", schema.Ref)
+		return "", false
+	}
+	return name, true
+}
+
+// isDefine returns true if the named schema becomes a define (class or enum), other schemas (like a string with a format)
+// are used in place of the references to them
+func (importer *schemaImporter) isDefine(schema *jsonSchema) bool {
+	return len(schema.Enum) > 0 || schema.Type.name() == "object" || len(schema.Properties.names) > 0 || len(schema.AllOf) > 0
+}
+
+// define creates the class or enum for a named schema
+func (importer *schemaImporter) define(name string, schema *jsonSchema) {
+	if !importer.isDefine(schema) {
+		if importer.options.Verbose > 0 {
+			log.Printf("Schema '%s' is not an object or enum, references to it use the type in place\n", name)
+		}
+		return
+	}
+	defineName := modelName(name)
+	if importer.defined[defineName] {
+		return
+	}
+	importer.defined[defineName] = true
+	if importer.options.Verbose > 0 {
+		log.Printf("Importing schema '%s' as '%s'\n", name, defineName)
+	}
+
+	if len(schema.Enum) > 0 {
+		importer.doc.Defines = append(importer.doc.Defines, importer.enum(defineName, schema))
+		return
+	}
+
+	define := XMLDefine{Type: "class", Name: defineName, Description: schema.Description, Pos: SourcePos{Line: schema.line}}
+	members := []*jsonSchema{schema}
+	if len(schema.AllOf) > 0 {
+		members = append(schema.AllOf, schema)
+	}
+	for _, member := range members {
+		if member.Ref != "" {
+			refName, ok := importer.refName(member)
+			if !ok {
+				continue
+			}
+			if define.Inherits == "" && importer.isDefine(importer.schemas[refName]) && len(importer.schemas[refName].Enum) == 0 {
+				define.Inherits = modelName(refName)
+				continue
+			}
+			importer.warnf(member.line, "'%s' can only inherit a single class, the properties of '%s' are copied", defineName, refName)
+			member = importer.schemas[refName]
+		}
+		required := make(map[string]bool)
+		for _, property := range member.Required {
+			required[property] = true
+		}
+		for _, property := range member.Properties.names {
+			if field, ok := importer.field(defineName, property, member.Properties.schemas[property], required[property]); ok {
+				define.Fields = append(define.Fields, field)
+			}
+		}
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		importer.warnf(schema.line, "oneOf/anyOf in '%s' is not supported, skipped", name)
+	}
+	if len(define.Fields) == 0 && define.Inherits == "" {
+		importer.warnf(schema.line, "schema '%s' has no properties", name)
+	}
+	importer.doc.Defines = append(importer.doc.Defines, define)
+}
+
+// enum creates an enum define, string values are numbered in declaration order
+func (importer *schemaImporter) enum(name string, schema *jsonSchema) XMLDefine {
+	define := XMLDefine{Type: "enum", Name: name, Description: schema.Description, Pos: SourcePos{Line: schema.line}}
+	numbered := false
+	for i, value := range schema.Enum {
+		enumValue := XMLDataTypeField{Value: i, Pos: SourcePos{Line: schema.line}}
+		switch v := value.(type) {
+		case int:
+			enumValue.Value = v
+			enumValue.Name = fmt.Sprintf("%s%d", name, v)
+		case nil:
+			continue
+		default:
+			numbered = true
+			enumValue.Name = name + modelName(nonIdentifierRegexp.ReplaceAllString(strings.ToLower(fmt.Sprint(v)), "_"))
+		}
+		if i < len(schema.EnumNames) {
+			enumValue.Name = modelName(schema.EnumNames[i])
+		}
+		define.Ints = append(define.Ints, enumValue)
+	}
+	if numbered {
+		importer.warnf(schema.line, "enum '%s' has string values, they are numbered in declaration order", name)
+	}
+	return define
+}
+
+// field creates a field for a property, returns false if the property can't be expressed in the model
+func (importer *schemaImporter) field(className string, property string, schema *jsonSchema, required bool) (XMLDataTypeField, bool) {
+	field := XMLDataTypeField{Name: modelName(property), Description: schema.Description, Pos: SourcePos{Line: schema.line}}
+	if !importer.applySchema(&field, className, schema, 0) {
+		return field, false
+	}
+	// A required number or bool can be zero, 'required' in the model means non zero
+	if required && (field.IsList || field.IsNullable() || !IsNumericGoType(field.Type) && field.Type != "bool") {
+		field.Required = true
+	}
+	importer.used[field.Type] = true
+	return field, true
+}
+
+// applySchema sets the type and constraints of a field from the schema of a property (or array items)
+func (importer *schemaImporter) applySchema(field *XMLDataTypeField, className string, schema *jsonSchema, depth int) bool {
+	if schema.Nullable || schema.Type.nullable() {
+		field.Nullable = true
+	}
+	if len(schema.AllOf) == 1 && schema.AllOf[0].Ref != "" {
+		// allOf with a single reference is used to add attributes to a reference
+		schema = &jsonSchema{Ref: schema.AllOf[0].Ref, line: schema.AllOf[0].line, Nullable: schema.Nullable}
+	}
+
+	if schema.Ref != "" {
+		refName, ok := importer.refName(schema)
+		if !ok {
+			return false
+		}
+		refSchema := importer.schemas[refName]
+		if importer.isDefine(refSchema) {
+			field.Type = modelName(refName)
+			return true
+		}
+		if depth > 8 {
+			importer.warnf(schema.line, "reference '%s' is too deeply nested", schema.Ref)
+			return false
+		}
+		return importer.applySchema(field, className, refSchema, depth+1)
+	}
+
+	switch schema.Type.name() {
+	case "string":
+		field.Type = "string"
+		if modelType, ok := schemaStringFormats[schema.Format]; ok {
+			field.Type = modelType
+		}
+		field.MinLen, field.MaxLen, field.Pattern = schema.MinLength, schema.MaxLength, schema.Pattern
+		if len(schema.Enum) > 0 && field.Pattern == "" {
+			values := []string{}
+			for _, value := range schema.Enum {
+				values = append(values, regexp.QuoteMeta(fmt.Sprint(value)))
+			}
+			field.Pattern = "^(" + strings.Join(values, "|") + ")$"
+		}
+	case "integer":
+		field.Type = "int"
+		if schema.Format == "int32" || schema.Format == "int64" {
+			field.Type = schema.Format
+		}
+		importer.applyRange(field, schema)
+	case "number":
+		field.Type = "float64"
+		if schema.Format == "float" {
+			field.Type = "float32"
+		}
+		importer.applyRange(field, schema)
+	case "boolean":
+		field.Type = "bool"
+	case "array":
+		if field.IsList {
+			importer.warnf(schema.line, "nested arrays ('%s::%s') are not supported, skipped", className, field.Name)
+			return false
+		}
+		if schema.Items == nil {
+			importer.warnf(schema.line, "array '%s::%s' has no 'items', skipped", className, field.Name)
+			return false
+		}
+		field.IsList = true
+		field.Nullable = false
+		if !importer.applySchema(field, className, schema.Items, depth+1) {
+			return false
+		}
+		field.MinLen, field.MaxLen = schema.MinItems, schema.MaxItems
+		return true
+	case "object", "":
+		if len(schema.Properties.names) == 0 {
+			importer.warnf(schema.line, "free form object '%s::%s' is not supported, skipped", className, field.Name)
+			return false
+		}
+		// inline object, becomes a class of its own
+		name := className + field.Name
+		if _, ok := importer.schemas[name]; ok {
+			importer.warnf(schema.line, "inline object '%s::%s' conflicts with schema '%s', skipped", className, field.Name, name)
+			return false
+		}
+		importer.schemas[name] = schema
+		importer.define(name, schema)
+		field.Type = name
+		return true
+	default:
+		importer.warnf(schema.line, "type '%s' of '%s::%s' is not supported, skipped", schema.Type.name(), className, field.Name)
+		return false
+	}
+	if schema.Default.Kind == yaml.ScalarNode && schema.Default.Tag != "!!null" {
+		value := schema.Default.Value
+		field.Default = &value
+	}
+	return true
+}
+
+func (importer *schemaImporter) applyRange(field *XMLDataTypeField, schema *jsonSchema) {
+	if schema.Minimum.Kind == yaml.ScalarNode {
+		field.Min = schema.Minimum.Value
+	}
+	if schema.Maximum.Kind == yaml.ScalarNode {
+		field.Max = schema.Maximum.Value
+	}
+}
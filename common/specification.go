@@ -4,37 +4,62 @@ package common
 // XML Import structures
 //
 type XMLDBControl struct {
-	Host     string `xml:"host"`
-	DBName   string `xml:"dbname"`
-	Schema   string `xml:"schema"`
-	User     string `xml:"user"`
-	Password string `xml:"password"`
+	Host     string `xml:"host,omitempty"`
+	DBName   string `xml:"dbname,omitempty"`
+	Schema   string `xml:"schema,omitempty"`
+	User     string `xml:"user,omitempty"`
+	Password string `xml:"password,omitempty"`
 }
 
 // XMLDataTypeField declares a variable in an definition (see XmlDefine)
 type XMLDataTypeField struct {
-	Name            string `xml:"name,attr"`
-	Default         string `xml:"default,attr"`
-	Value           int    `xml:"value,attr"`
-	DBSize          int    `xml:"dbsize,attr"`
-	FieldSize       int    `xml:"fieldsize,attr"`
-	Type            string `xml:"type,attr"`
-	IsPointer       bool   `xml:"ispointer,attr"`
-	IsList          bool   `xml:"islist,attr"`
-	FromVersion     int    `xml:"fromversion,attr"`
-	SkipPersistance bool   `xml:"nopersist,attr"`
-	DBAutoID        bool   `xml:"dbautoid,attr"`
-	XMLAttrib       string `xml:"xmlattrib,attr"`
+	Name            string  `xml:"name,attr,omitempty"`
+	Default         *string `xml:"default,attr,omitempty"` // nil without default, an empty string is a default as well
+	Value           int     `xml:"value,attr,omitempty"`
+	DBSize          int     `xml:"dbsize,attr,omitempty"`
+	FieldSize       int     `xml:"fieldsize,attr,omitempty"`
+	Type            string  `xml:"type,attr,omitempty"`
+	IsPointer       bool    `xml:"ispointer,attr,omitempty"`
+	IsList          bool    `xml:"islist,attr,omitempty"`
+	FromVersion     int     `xml:"fromversion,attr,omitempty"`
+	ToVersion       int     `xml:"toversion,attr,omitempty"`
+	Removed         bool    `xml:"removed,attr,omitempty"`
+	SkipPersistance bool    `xml:"nopersist,attr,omitempty"`
+	DBAutoID        bool    `xml:"dbautoid,attr,omitempty"`
+	XMLAttrib       string  `xml:"xmlattrib,attr,omitempty"`
+	PrimaryKey      bool    `xml:"primarykey,attr,omitempty"`
+	Nullable        bool    `xml:"nullable,attr,omitempty"`
+	Required        bool    `xml:"required,attr,omitempty"`
+	Min             string  `xml:"min,attr,omitempty"`
+	Max             string  `xml:"max,attr,omitempty"`
+	MinLen          int     `xml:"minlen,attr,omitempty"`
+	MaxLen          int     `xml:"maxlen,attr,omitempty"`
+	Pattern         string  `xml:"pattern,attr,omitempty"`
+	References      string  `xml:"references,attr,omitempty"`
+	OnDelete        string  `xml:"ondelete,attr,omitempty"`
+	Relation        string  `xml:"relation,attr,omitempty"`
+	MappedBy        string  `xml:"mappedby,attr,omitempty"`
+	Description     string  `xml:"description,attr,omitempty"`
+	Doc             string  `xml:"doc,omitempty"`
+
+	Pos SourcePos `xml:"-"`
 }
 
 // XMLDefine declares an object (type/struct)
 type XMLDefine struct {
-	Type            string             `xml:"type,attr"`
-	Name            string             `xml:"name,attr"`
-	Inherits        string             `xml:"inherits,attr"`
-	DBSchema        string             `xml:"dbschema,attr"`
-	Prefix          string             `xml:"prefix,attr"`
-	SkipPersistance bool               `xml:"nopersist,attr"`
+	Type            string             `xml:"type,attr,omitempty"`
+	Name            string             `xml:"name,attr,omitempty"`
+	Inherits        string             `xml:"inherits,attr,omitempty"`
+	Inheritance     string             `xml:"inheritance,attr,omitempty"`
+	DBSchema        string             `xml:"dbschema,attr,omitempty"`
+	Prefix          string             `xml:"prefix,attr,omitempty"`
+	SkipPersistance bool               `xml:"nopersist,attr,omitempty"`
+	Override        bool               `xml:"override,attr,omitempty"`
+	FromVersion     int                `xml:"fromversion,attr,omitempty"`
+	ToVersion       int                `xml:"toversion,attr,omitempty"`
+	Removed         bool               `xml:"removed,attr,omitempty"`
+	Description     string             `xml:"description,attr,omitempty"`
+	Doc             string             `xml:"doc,omitempty"`
 	Fields          []XMLDataTypeField `xml:"field"`
 	Guids           []XMLDataTypeField `xml:"guid"`
 	Strings         []XMLDataTypeField `xml:"string"`
@@ -44,36 +69,58 @@ type XMLDefine struct {
 	Lists           []XMLDataTypeField `xml:"list"`
 	Objects         []XMLDataTypeField `xml:"object"`
 	Enums           []XMLDataTypeField `xml:"enum"`
+	Indexes         []XMLIndex         `xml:"index"`
+	Uniques         []XMLIndex         `xml:"unique"`
+
+	Pos SourcePos `xml:"-"`
+}
+
+// XMLIndex declares an index or unique constraint on one or more fields of a class
+type XMLIndex struct {
+	Name        string `xml:"name,attr,omitempty"`
+	Fields      string `xml:"fields,attr,omitempty"`
+	Prefix      int    `xml:"prefix,attr,omitempty"`
+	FromVersion int    `xml:"fromversion,attr,omitempty"`
 
-	// private stuff
-	Methods []AccessMethod
+	Pos SourcePos `xml:"-"`
 }
 
 // XMLImport holds import directives
 type XMLImport struct {
-	DisablePersistence bool   `xml:"no_persistence,attr"`
+	DisablePersistence bool   `xml:"no_persistence,attr,omitempty"`
 	Package            string `xml:",innerxml"`
 }
 
 // XMLTypeMapping Holds type mappings definitions
 type XMLTypeMapping struct {
-	Lang      string `xml:"lang,attr"`
-	FromType  string `xml:"from,attr"`
-	ToType    string `xml:"to,attr"`
-	Encode    string `xml:"encode,attr"`
-	Decode    string `xml:"decode,attr"`
-	FieldSize int    `xml:"fieldsize,attr"`
+	Lang      string `xml:"lang,attr,omitempty"`
+	FromType  string `xml:"from,attr,omitempty"`
+	ToType    string `xml:"to,attr,omitempty"`
+	Encode    string `xml:"encode,attr,omitempty"`
+	Decode    string `xml:"decode,attr,omitempty"`
+	FieldSize int    `xml:"fieldsize,attr,omitempty"`
+
+	Pos SourcePos `xml:"-"`
 }
 
+// Include modes ('mode' attribute), a merged include is copied into the including document,
+// the types of a referenced include stay in the namespace of the included document
+const (
+	IncludeMerge     = "merge"
+	IncludeReference = "reference"
+)
+
+// XMLInclude holds an include directive
 type XMLInclude struct {
+	Mode     string `xml:"mode,attr,omitempty"`
 	Filename string `xml:",innerxml"`
-	Document XMLDoc
+	Document XMLDoc `xml:"-"`
 }
 
 // XMLDoc holds the document root
 type XMLDoc struct {
-	Namespace       string           `xml:"namespace,attr"`
-	DBSchema        string           `xml:"dbschema,attr"`
+	Namespace       string           `xml:"namespace,attr,omitempty"`
+	DBSchema        string           `xml:"dbschema,attr,omitempty"`
 	Includes        []XMLInclude     `xml:"include"`
 	Imports         []XMLImport      `xml:"imports>package"`
 	Defines         []XMLDefine      `xml:"define"`
@@ -81,4 +128,7 @@ type XMLDoc struct {
 	GOTypeMappings  []XMLTypeMapping `xml:"gotypemappings>map"`
 	AnyTypeMappings []XMLTypeMapping `xml:"anytypemappings>map"`
 	DBControl       XMLDBControl     `xml:"dbcontrol"`
+
+	Filename   string    `xml:"-"`
+	References []*XMLDoc `xml:"-"` // documents included with mode="reference"
 }
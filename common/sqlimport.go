@@ -0,0 +1,760 @@
+package common
+
+//
+// Reverse engineering of a model from a MySQL DDL dump (mysqldump --no-data)
+// Only CREATE TABLE (and USE) statements are used, everything else in the dump is skipped
+//
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // keywords, unquoted identifiers and numbers
+	sqlQuoted                     // `identifier`
+	sqlString                     // 'string' or "string"
+	sqlPunct                      // ( ) , ; and any other single character
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	line int
+}
+
+// is returns true if the token is the (case insensitive) keyword or punctuation
+func (token sqlToken) is(text string) bool {
+	return (token.kind == sqlWord || token.kind == sqlPunct) && strings.EqualFold(token.text, text)
+}
+
+// isName returns true if the token can be used as a name (table, column, index)
+func (token sqlToken) isName() bool {
+	return token.kind == sqlWord || token.kind == sqlQuoted
+}
+
+// tokenizeSQL splits the dump in tokens, comments (including the /*!...*/ version comments of mysqldump) are dropped
+func tokenizeSQL(data string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	line := 1
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || (c == '-' && strings.HasPrefix(data[i:], "--")):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(data[i:], "/*"):
+			end := strings.Index(data[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(data[i:i+2+end], "\n")
+			i += end + 4
+		case c == '`' || c == '\'' || c == '"':
+			start := line
+			text := strings.Builder{}
+			j := i + 1
+			for ; j < len(data); j++ {
+				if data[j] == '\\' && c != '`' && j+1 < len(data) {
+					j++
+					if data[j] == 'n' {
+						text.WriteByte('\n')
+					} else {
+						text.WriteByte(data[j])
+					}
+					continue
+				}
+				if data[j] == c {
+					if j+1 < len(data) && data[j+1] == c {
+						// doubled quote
+						j++
+						text.WriteByte(c)
+						continue
+					}
+					break
+				}
+				if data[j] == '\n' {
+					line++
+				}
+				text.WriteByte(data[j])
+			}
+			if j >= len(data) {
+				return nil, fmt.Errorf("line %d: unterminated quote", start)
+			}
+			kind := sqlString
+			if c == '`' {
+				kind = sqlQuoted
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text.String(), line: start})
+			i = j + 1
+		case isSQLWordChar(c):
+			j := i
+			for j < len(data) && isSQLWordChar(data[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: data[i:j], line: line})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{kind: sqlPunct, text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isSQLWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// splitSQL splits a token list on the separator, only at the top level (not inside parentheses)
+func splitSQL(tokens []sqlToken, separator string) [][]sqlToken {
+	parts := [][]sqlToken{}
+	depth, start := 0, 0
+	for i, token := range tokens {
+		switch {
+		case token.is("("):
+			depth++
+		case token.is(")"):
+			depth--
+		case depth == 0 && token.is(separator):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+// group returns the tokens between the parenthesis at tokens[0] and its matching parenthesis, and the tokens after it
+func group(tokens []sqlToken) ([]sqlToken, []sqlToken, bool) {
+	if len(tokens) == 0 || !tokens[0].is("(") {
+		return nil, tokens, false
+	}
+	depth := 0
+	for i, token := range tokens {
+		if token.is("(") {
+			depth++
+		} else if token.is(")") {
+			depth--
+			if depth == 0 {
+				return tokens[1:i], tokens[i+1:], true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// sqlIndexColumn is a column in a key definition with its optional prefix length
+type sqlIndexColumn struct {
+	name   string
+	prefix string
+}
+
+type sqlForeignKey struct {
+	column   string
+	table    string
+	refTable string
+	refCol   string
+	onDelete string
+	line     int
+}
+
+type sqlTable struct {
+	name    string
+	define  XMLDefine
+	columns map[string]int // column name to index in define.Fields
+}
+
+// sqlImporter holds the state while importing a dump
+type sqlImporter struct {
+	filename string
+	options  *Options
+	doc      XMLDoc
+	tables   []*sqlTable
+	byName   map[string]*sqlTable
+	fks      []sqlForeignKey
+
+	dbTypes map[string]int // model type to index in doc.DBTypeMappings
+	goTypes map[string]bool
+}
+
+// ImportSQLFile reads a MySQL DDL dump and creates a model from the CREATE TABLE statements, see ImportSQL
+func ImportSQLFile(filename string, options *Options) (XMLDoc, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return XMLDoc{}, err
+	}
+	return ImportSQL(filename, data, options)
+}
+
+// ImportSQL creates a model from the CREATE TABLE statements of a MySQL DDL dump.
+// Table and column names are turned into class and field names (the table prefix, options.DBTablePrefix, is stripped),
+// the column types become db/go type mappings. Constructs the generators can't reproduce are reported as warnings.
+func ImportSQL(filename string, data []byte, options *Options) (XMLDoc, error) {
+	tokens, err := tokenizeSQL(string(data))
+	if err != nil {
+		return XMLDoc{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	importer := sqlImporter{
+		filename: filename,
+		options:  options,
+		byName:   make(map[string]*sqlTable),
+		dbTypes:  make(map[string]int),
+		goTypes:  make(map[string]bool),
+	}
+	for _, statement := range splitSQL(tokens, ";") {
+		if err := importer.statement(statement); err != nil {
+			return XMLDoc{}, fmt.Errorf("%s:%d: %w", filename, statement[0].line, err)
+		}
+	}
+	if len(importer.tables) == 0 {
+		return XMLDoc{}, fmt.Errorf("%s: no CREATE TABLE statements found", filename)
+	}
+	importer.resolveForeignKeys()
+
+	doc := importer.doc
+	doc.Namespace = importer.namespace()
+	for _, table := range importer.tables {
+		doc.Defines = append(doc.Defines, table.define)
+	}
+	doc.SetSourceFile(filename)
+	return doc, nil
+}
+
+func (importer *sqlImporter) warnf(line int, format string, args ...interface{}) {
+	log.Printf("!WARNING!: %s:%d: %s\n", importer.filename, line, fmt.Sprintf(format, args...))
+}
+
+func (importer *sqlImporter) statement(tokens []sqlToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	if tokens[0].is("USE") && len(tokens) > 1 {
+		importer.doc.DBControl.DBName = tokens[1].text
+		return nil
+	}
+	if len(tokens) < 3 || !tokens[0].is("CREATE") {
+		return nil
+	}
+	rest := tokens[1:]
+	if rest[0].is("TEMPORARY") {
+		rest = rest[1:]
+	}
+	if !rest[0].is("TABLE") {
+		return nil
+	}
+	rest = rest[1:]
+	if len(rest) > 3 && rest[0].is("IF") && rest[1].is("NOT") && rest[2].is("EXISTS") {
+		rest = rest[3:]
+	}
+	if len(rest) == 0 || !rest[0].isName() {
+		return fmt.Errorf("CREATE TABLE without table name")
+	}
+	tableName := rest[0].text
+	if len(rest) > 2 && rest[1].text == "." && rest[2].isName() {
+		// `schema`.`table`
+		tableName = rest[2].text
+		rest = rest[2:]
+	} else if idx := strings.LastIndex(tableName, "."); idx >= 0 && rest[0].kind == sqlWord {
+		tableName = tableName[idx+1:]
+	}
+	body, options, ok := group(rest[1:])
+	if !ok {
+		importer.warnf(tokens[0].line, "skipping table '%s', only CREATE TABLE with column definitions is supported", tableName)
+		return nil
+	}
+	return importer.table(tableName, tokens[0].line, body, tableComment(options))
+}
+
+// tableComment returns the COMMENT of the table options (like 'ENGINE=InnoDB COMMENT='...''), empty if there is none
+func tableComment(options []sqlToken) string {
+	for i := 0; i < len(options)-1; i++ {
+		if !options[i].is("COMMENT") {
+			continue
+		}
+		if options[i+1].text == "=" {
+			i++
+		}
+		if i+1 < len(options) && options[i+1].kind == sqlString {
+			return options[i+1].text
+		}
+	}
+	return ""
+}
+
+func (importer *sqlImporter) table(tableName string, line int, body []sqlToken, comment string) error {
+	if _, ok := importer.byName[tableName]; ok {
+		importer.warnf(line, "table '%s' is created twice, using the first definition", tableName)
+		return nil
+	}
+	name := tableName
+	if prefix := importer.options.DBTablePrefix; prefix != "" {
+		if strings.HasPrefix(name, prefix) {
+			name = name[len(prefix):]
+		} else {
+			importer.warnf(line, "table '%s' doesn't have the table prefix '%s', the generated table name will differ", tableName, prefix)
+		}
+	}
+	table := &sqlTable{
+		name:    tableName,
+		define:  XMLDefine{Type: "class", Name: modelName(name), Description: comment, Pos: SourcePos{Line: line}},
+		columns: make(map[string]int),
+	}
+	if strings.ToLower(table.define.Name) != strings.ToLower(name) {
+		importer.warnf(line, "table name '%s' can't be used as class name, using '%s'", name, table.define.Name)
+	}
+	if importer.options.Verbose > 0 {
+		log.Printf("Importing table '%s' as class '%s'\n", tableName, table.define.Name)
+	}
+
+	// Columns first, the keys refer to them
+	definitions := splitSQL(body, ",")
+	keys := [][]sqlToken{}
+	for _, definition := range definitions {
+		if len(definition) == 0 {
+			continue
+		}
+		first := definition[0]
+		if first.kind == sqlWord && isKeyDefinition(first.text) {
+			keys = append(keys, definition)
+			continue
+		}
+		if err := importer.column(table, definition); err != nil {
+			return err
+		}
+	}
+	if len(table.define.Fields) == 0 {
+		return fmt.Errorf("table '%s' has no columns", tableName)
+	}
+	for _, definition := range keys {
+		importer.key(table, definition)
+	}
+	if len(table.define.PrimaryKeys()) == 0 || !table.define.PrimaryKeys()[0].PrimaryKey {
+		importer.warnf(line, "table '%s' has no primary key, the first column '%s' is used", tableName, table.define.Fields[0].Name)
+	}
+
+	importer.tables = append(importer.tables, table)
+	importer.byName[tableName] = table
+	return nil
+}
+
+func isKeyDefinition(word string) bool {
+	switch strings.ToUpper(word) {
+	case "PRIMARY", "UNIQUE", "KEY", "INDEX", "FULLTEXT", "SPATIAL", "CONSTRAINT", "FOREIGN", "CHECK":
+		return true
+	}
+	return false
+}
+
+func (importer *sqlImporter) column(table *sqlTable, tokens []sqlToken) error {
+	if !tokens[0].isName() || len(tokens) < 2 || tokens[1].kind != sqlWord {
+		return fmt.Errorf("invalid column definition in table '%s'", table.name)
+	}
+	column, line := tokens[0].text, tokens[0].line
+	field := XMLDataTypeField{Name: modelName(column), Nullable: true, Pos: SourcePos{Line: line}}
+	if strings.ToLower(field.Name) != strings.ToLower(column) {
+		importer.warnf(line, "column name '%s.%s' can't be used as field name, using '%s'", table.name, column, field.Name)
+	}
+
+	// Type with optional arguments and modifiers
+	baseType := strings.ToLower(tokens[1].text)
+	rest := tokens[2:]
+	args := []string{}
+	if argTokens, after, ok := group(rest); ok {
+		for _, arg := range splitSQL(argTokens, ",") {
+			if len(arg) == 1 && arg[0].kind == sqlString {
+				args = append(args, "'"+strings.Replace(arg[0].text, "'", "''", -1)+"'")
+			} else if len(arg) > 0 {
+				args = append(args, arg[0].text)
+			}
+		}
+		rest = after
+	}
+	modifiers := []string{}
+	for len(rest) > 0 && (rest[0].is("UNSIGNED") || rest[0].is("ZEROFILL")) {
+		modifiers = append(modifiers, strings.ToLower(rest[0].text))
+		rest = rest[1:]
+	}
+	importer.columnType(&field, baseType, args, modifiers, line)
+
+	for len(rest) > 0 {
+		token := rest[0]
+		rest = rest[1:]
+		switch {
+		case token.is("NOT") && len(rest) > 0 && rest[0].is("NULL"):
+			field.Nullable = false
+			rest = rest[1:]
+		case token.is("NULL"):
+			field.Nullable = true
+		case token.is("AUTO_INCREMENT"):
+			field.DBAutoID = true
+		case token.is("PRIMARY"):
+			field.PrimaryKey = true
+			if len(rest) > 0 && rest[0].is("KEY") {
+				rest = rest[1:]
+			}
+		case token.is("UNIQUE"):
+			if len(rest) > 0 && rest[0].is("KEY") {
+				rest = rest[1:]
+			}
+			table.define.Uniques = append(table.define.Uniques, XMLIndex{Fields: field.Name, Pos: SourcePos{Line: line}})
+		case token.is("DEFAULT") && len(rest) > 0:
+			value := rest[0]
+			rest = rest[1:]
+			switch {
+			case value.kind == sqlString || (value.kind == sqlWord && isSQLNumber(value.text)):
+				// An empty string is kept as default="", the most common default of a NOT NULL column
+				field.Default = &value.text
+			case value.is("-") && len(rest) > 0:
+				negative := "-" + rest[0].text
+				field.Default = &negative
+				rest = rest[1:]
+			case value.is("NULL"):
+			default:
+				importer.warnf(line, "default '%s' of '%s.%s' is not a literal, it is not imported", value.text, table.name, column)
+				if _, after, ok := group(rest); ok {
+					rest = after
+				}
+			}
+		case token.is("COMMENT") && len(rest) > 0 && rest[0].kind == sqlString:
+			field.Description = rest[0].text
+			rest = rest[1:]
+		case token.is("COMMENT") || token.is("COLLATE") || token.is("CHARSET"):
+			if len(rest) > 0 {
+				rest = rest[1:]
+			}
+		case token.is("CHARACTER") && len(rest) > 1 && rest[0].is("SET"):
+			rest = rest[2:]
+		case token.is("ON") && len(rest) > 1 && rest[0].is("UPDATE"):
+			importer.warnf(line, "'ON UPDATE %s' of '%s.%s' is not supported by the generator", rest[1].text, table.name, column)
+			rest = rest[2:]
+			if _, after, ok := group(rest); ok {
+				rest = after
+			}
+		default:
+			if importer.options.Verbose > 0 {
+				log.Printf("Ignoring '%s' in definition of '%s.%s'\n", token.text, table.name, column)
+			}
+		}
+	}
+
+	if _, ok := table.columns[column]; ok {
+		return fmt.Errorf("duplicate column '%s' in table '%s'", column, table.name)
+	}
+	table.columns[column] = len(table.define.Fields)
+	table.define.Fields = append(table.define.Fields, field)
+	return nil
+}
+
+var sqlNumberRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+func isSQLNumber(text string) bool {
+	return sqlNumberRegexp.MatchString(text)
+}
+
+// sqlTypeInfo describes how a column type is imported, the model type is used as long as it maps to the same DB type
+type sqlTypeInfo struct {
+	modelType string
+	goType    string
+	sized     bool // the first argument is the field size ('varchar(%d)')
+}
+
+var sqlTypes = map[string]sqlTypeInfo{
+	"tinyint":    {"int8", "int8", false},
+	"smallint":   {"int16", "int16", false},
+	"mediumint":  {"int", "int", false},
+	"int":        {"int", "int", false},
+	"integer":    {"int", "int", false},
+	"bigint":     {"int64", "int64", false},
+	"float":      {"float32", "float32", false},
+	"double":     {"float64", "float64", false},
+	"real":       {"float64", "float64", false},
+	"decimal":    {"decimal", "float64", false},
+	"numeric":    {"decimal", "float64", false},
+	"char":       {"char", "string", true},
+	"varchar":    {"string", "string", true},
+	"tinytext":   {"tinytext", "string", false},
+	"text":       {"text", "string", false},
+	"mediumtext": {"mediumtext", "string", false},
+	"longtext":   {"longtext", "string", false},
+	"binary":     {"binary", "[]byte", true},
+	"varbinary":  {"varbinary", "[]byte", true},
+	"tinyblob":   {"tinyblob", "[]byte", false},
+	"blob":       {"blob", "[]byte", false},
+	"mediumblob": {"mediumblob", "[]byte", false},
+	"longblob":   {"longblob", "[]byte", false},
+	"datetime":   {"time", "time.Time", false},
+	"timestamp":  {"timestamp", "time.Time", false},
+	"date":       {"date", "time.Time", false},
+	"time":       {"timeofday", "string", false},
+	"year":       {"year", "int", false},
+	"json":       {"json", "string", false},
+	"enum":       {"enum", "string", false},
+	"set":        {"set", "string", false},
+	"bit":        {"bit", "[]byte", false},
+}
+
+var unsignedGoTypes = map[string]string{
+	"int8": "uint8", "int16": "uint16", "int": "uint32", "int64": "uint64",
+}
+
+var nonIdentifierRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// columnType sets the field type and adds the db/go type mappings for the column type
+func (importer *sqlImporter) columnType(field *XMLDataTypeField, baseType string, args []string, modifiers []string, line int) {
+	info, ok := sqlTypes[baseType]
+	if !ok {
+		importer.warnf(line, "unknown column type '%s', imported as string", baseType)
+		info = sqlTypeInfo{modelType: baseType, goType: "string"}
+	}
+	if baseType == "tinyint" && len(args) == 1 && args[0] == "1" && len(modifiers) == 0 {
+		info = sqlTypeInfo{modelType: "bool", goType: "bool"}
+	}
+	unsigned := len(modifiers) > 0 && modifiers[0] == "unsigned"
+	if goType, ok := unsignedGoTypes[info.goType]; ok && unsigned {
+		info.modelType, info.goType = goType, goType
+	}
+
+	dbType, fieldSize := baseType, 0
+	if info.sized && len(args) == 1 {
+		dbType += "(%d)"
+		fmt.Sscanf(args[0], "%d", &fieldSize)
+	} else if len(args) > 0 {
+		dbType += "(" + strings.Join(args, ",") + ")"
+	}
+	if len(modifiers) > 0 {
+		dbType += " " + strings.Join(modifiers, " ")
+	}
+
+	// A model type can only map to a single DB type, other variants get a type named after the DB type
+	modelType := info.modelType
+	if index, ok := importer.dbTypes[modelType]; ok && importer.doc.DBTypeMappings[index].ToType != dbType {
+		modelType = strings.Trim(nonIdentifierRegexp.ReplaceAllString(strings.Replace(dbType, "%d", "", 1), "_"), "_")
+		if index, ok := importer.dbTypes[modelType]; ok && importer.doc.DBTypeMappings[index].ToType != dbType {
+			modelType = fmt.Sprintf("%s_%d", modelType, len(importer.dbTypes))
+		}
+	}
+
+	field.Type = modelType
+	if index, ok := importer.dbTypes[modelType]; ok {
+		if mapping := importer.doc.DBTypeMappings[index]; mapping.FieldSize != fieldSize {
+			field.FieldSize = fieldSize
+		}
+	} else {
+		importer.dbTypes[modelType] = len(importer.doc.DBTypeMappings)
+		importer.doc.DBTypeMappings = append(importer.doc.DBTypeMappings, XMLTypeMapping{FromType: modelType, ToType: dbType, FieldSize: fieldSize})
+	}
+	if modelType != info.goType && !importer.goTypes[modelType] {
+		importer.goTypes[modelType] = true
+		importer.doc.GOTypeMappings = append(importer.doc.GOTypeMappings, XMLTypeMapping{FromType: modelType, ToType: info.goType})
+		if strings.HasPrefix(info.goType, "time.") && !hasImport(importer.doc.Imports, XMLImport{Package: "time"}) {
+			importer.doc.Imports = append(importer.doc.Imports, XMLImport{Package: "time"})
+		}
+	}
+}
+
+// key imports a key, index or constraint definition of a table
+func (importer *sqlImporter) key(table *sqlTable, tokens []sqlToken) {
+	line := tokens[0].line
+	first := strings.ToUpper(tokens[0].text)
+	rest := tokens[1:]
+	if first == "CONSTRAINT" {
+		if len(rest) > 0 && rest[0].isName() && !rest[0].is("FOREIGN") && !rest[0].is("CHECK") && !rest[0].is("PRIMARY") && !rest[0].is("UNIQUE") {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return
+		}
+		first = strings.ToUpper(rest[0].text)
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && (rest[0].is("KEY") || rest[0].is("INDEX")) {
+		rest = rest[1:]
+	}
+	name := ""
+	if len(rest) > 0 && rest[0].isName() {
+		name = rest[0].text
+		rest = rest[1:]
+	}
+	columns := importer.keyColumns(table, rest, line)
+
+	switch first {
+	case "PRIMARY":
+		for _, column := range columns {
+			if column.prefix != "" {
+				importer.warnf(line, "prefix length on primary key column '%s.%s' is not supported", table.name, column.name)
+			}
+			table.define.Fields[table.columns[column.name]].PrimaryKey = true
+		}
+	case "UNIQUE", "KEY", "INDEX":
+		if len(columns) == 0 {
+			return
+		}
+		index := XMLIndex{Name: name, Fields: importer.indexFields(table, columns), Pos: SourcePos{Line: line}}
+		unique := first == "UNIQUE"
+		if index.Name == (&XMLIndex{Fields: index.Fields}).IndexName(&table.define, unique) {
+			// the generator creates the same name
+			index.Name = ""
+		}
+		if unique {
+			table.define.Uniques = append(table.define.Uniques, index)
+		} else {
+			table.define.Indexes = append(table.define.Indexes, index)
+		}
+	case "FOREIGN":
+		importer.foreignKey(table, columns, rest, line)
+	default:
+		importer.warnf(line, "%s definition in table '%s' is not supported, skipped", first, table.name)
+	}
+}
+
+// keyColumns parses a column list like '(`path`(64),`name`)', unknown columns are reported and dropped
+func (importer *sqlImporter) keyColumns(table *sqlTable, tokens []sqlToken, line int) []sqlIndexColumn {
+	columns := []sqlIndexColumn{}
+	list, _, ok := group(tokens)
+	if !ok {
+		return columns
+	}
+	for _, part := range splitSQL(list, ",") {
+		if len(part) == 0 || !part[0].isName() {
+			continue
+		}
+		column := sqlIndexColumn{name: part[0].text}
+		if prefix, _, ok := group(part[1:]); ok && len(prefix) == 1 {
+			column.prefix = prefix[0].text
+		}
+		if _, ok := table.columns[column.name]; !ok {
+			importer.warnf(line, "key in table '%s' uses unknown column '%s'", table.name, column.name)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// indexFields returns the 'fields' attribute for an index on the columns
+func (importer *sqlImporter) indexFields(table *sqlTable, columns []sqlIndexColumn) string {
+	fields := []string{}
+	for _, column := range columns {
+		name := table.define.Fields[table.columns[column.name]].Name
+		if column.prefix != "" {
+			name += "(" + column.prefix + ")"
+		}
+		fields = append(fields, name)
+	}
+	return strings.Join(fields, ",")
+}
+
+func (importer *sqlImporter) foreignKey(table *sqlTable, columns []sqlIndexColumn, tokens []sqlToken, line int) {
+	_, rest, _ := group(tokens)
+	if len(rest) < 2 || !rest[0].is("REFERENCES") || !rest[1].isName() {
+		importer.warnf(line, "invalid foreign key in table '%s', skipped", table.name)
+		return
+	}
+	fk := sqlForeignKey{table: table.name, refTable: rest[1].text, line: line}
+	refList, rest, _ := group(rest[2:])
+	refColumns := []string{}
+	for _, part := range splitSQL(refList, ",") {
+		if len(part) > 0 {
+			refColumns = append(refColumns, part[0].text)
+		}
+	}
+	if len(columns) != 1 || len(refColumns) != 1 {
+		importer.warnf(line, "foreign key on several columns in table '%s' is not supported, skipped", table.name)
+		return
+	}
+	fk.column, fk.refCol = columns[0].name, refColumns[0]
+
+	for len(rest) > 2 {
+		if !rest[0].is("ON") {
+			rest = rest[1:]
+			continue
+		}
+		action := strings.ToLower(rest[2].text)
+		consumed := 3
+		if len(rest) > 3 && (rest[2].is("SET") || rest[2].is("NO")) {
+			action += strings.ToLower(rest[3].text)
+			consumed = 4
+		}
+		if rest[1].is("DELETE") {
+			fk.onDelete = action
+		} else if action != "restrict" && action != "noaction" {
+			importer.warnf(line, "'ON UPDATE' of the foreign key on '%s.%s' is not supported by the generator", table.name, fk.column)
+		}
+		rest = rest[consumed:]
+	}
+	importer.fks = append(importer.fks, fk)
+}
+
+// resolveForeignKeys turns the foreign keys into 'references' once all tables are known
+func (importer *sqlImporter) resolveForeignKeys() {
+	for _, fk := range importer.fks {
+		refTable, ok := importer.byName[fk.refTable]
+		if !ok {
+			importer.warnf(fk.line, "foreign key on '%s.%s' references table '%s' which is not in the dump, skipped", fk.table, fk.column, fk.refTable)
+			continue
+		}
+		refIndex, ok := refTable.columns[fk.refCol]
+		if !ok {
+			importer.warnf(fk.line, "foreign key on '%s.%s' references unknown column '%s.%s', skipped", fk.table, fk.column, fk.refTable, fk.refCol)
+			continue
+		}
+		table := importer.byName[fk.table]
+		field := &table.define.Fields[table.columns[fk.column]]
+		field.References = refTable.define.Name + "." + refTable.define.Fields[refIndex].Name
+		if fk.onDelete != "restrict" {
+			field.OnDelete = fk.onDelete
+		}
+	}
+}
+
+// namespace returns the namespace for the imported model, the database name or the name of the dump file
+func (importer *sqlImporter) namespace() string {
+	if importer.doc.DBControl.DBName != "" {
+		return importNamespace(importer.doc.DBControl.DBName)
+	}
+	return importNamespace(importer.filename)
+}
+
+// importNamespace turns a (file) name into a namespace for an imported model, 'path/to/Shop-API.yaml' becomes 'shop_api'
+func importNamespace(name string) string {
+	name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	name = strings.Trim(nonIdentifierRegexp.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "model" + name
+	}
+	return name
+}
+
+// modelName turns a table or column name into a class or field name. The generators use the lower case name
+// for tables and columns so only the case is changed ('user_account' becomes 'User_Account'), unless the name isn't a valid identifier.
+func modelName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	result := strings.Join(parts, "_")
+	if !identifierRegexp.MatchString(result) {
+		result = strings.Trim(regexp.MustCompile(`[^A-Za-z0-9_]+`).ReplaceAllString(result, "_"), "_")
+		if result == "" || (result[0] >= '0' && result[0] <= '9') {
+			result = "X" + result
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package common
+
+//
+// Code templates, the generators execute text/template templates embedded in the language package.
+// A template directory ('--templates', 'templates' in the project file) overrides single templates: the '*.tmpl'
+// files in '<dir>/<language>' are parsed after the embedded templates, a {{define}} there replaces the embedded one.
+//
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Templates is the template set of a language, see LoadTemplates
+type Templates struct {
+	language string
+	set      *template.Template
+}
+
+//
+// Parses the templates embedded in the language package ('templates/*.tmpl' of the file system) and the overrides
+// in the template directory of the options, funcs are the helper functions of the language
+//
+func LoadTemplates(language string, embedded fs.FS, options *Options, funcs template.FuncMap) (*Templates, error) {
+	templates := &Templates{language: language}
+	set := template.New(language).Funcs(TemplateFuncs()).Funcs(template.FuncMap{
+		// include executes a template and returns the output, so a template can be used as a value
+		"include": func(name string, data interface{}) (string, error) {
+			return templates.execute(name, data)
+		},
+	}).Funcs(funcs)
+
+	set, err := set.ParseFS(embedded, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if options.TemplateDirectory != "" {
+		if info, err := os.Stat(options.TemplateDirectory); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("template directory '%s' not found", options.TemplateDirectory)
+		}
+		files, err := filepath.Glob(filepath.Join(options.TemplateDirectory, language, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			if options.Verbose > 0 {
+				log.Printf("Template overrides for %s: %s\n", language, strings.Join(files, ", "))
+			}
+			if set, err = set.ParseFiles(files...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	templates.set = set
+	return templates, nil
+}
+
+//
+// Executes a template, as the model is valid a failing template is an error in an override
+//
+func (templates *Templates) Execute(name string, data interface{}) (string, error) {
+	code, err := templates.execute(name, data)
+	if err != nil {
+		return "", fmt.Errorf("template error (%s): %w", templates.language, err)
+	}
+	return code, nil
+}
+
+func (templates *Templates) execute(name string, data interface{}) (string, error) {
+	var code strings.Builder
+	if err := templates.set.ExecuteTemplate(&code, name, data); err != nil {
+		return "", err
+	}
+	return code.String(), nil
+}
+
+//
+// Helper functions available in the templates of all languages, name casing and type mapping
+//
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":  strings.ToLower,
+		"upper":  strings.ToUpper,
+		"pascal": PascalCase,
+		"camel":  CamelCase,
+		"snake":  SnakeCase,
+		"join":   strings.Join,
+		// mappedType returns the type of a field in a language (go, cpp, ts or db), see Field.MappedType
+		"mappedType": func(field *Field, lang string) string {
+			return field.MappedType(lang)
+		},
+		// mapping returns the type mapping of a field in a language, nil if the type is not mapped
+		"mapping": func(field *Field, lang string) *XMLTypeMapping {
+			return field.Mapping(lang)
+		},
+	}
+}
+
+// PascalCase returns the name with an upper case initial, 'userID' becomes 'UserID'
+func PascalCase(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// CamelCase returns the name with a lower case initial word, 'UserID' becomes 'userID', 'ID' becomes 'id' and 'URLPath' becomes 'urlPath'
+func CamelCase(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// Keep the last upper case letter of an acronym if it starts the next word
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	if upper == 0 {
+		upper = 1
+	}
+	if upper > len(runes) {
+		return name
+	}
+	return strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+}
+
+// SnakeCase returns the name in lower case words separated by '_', 'UserID' becomes 'user_id' and 'URLPath' becomes 'url_path'
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	result := []rune{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previous != '_' && (unicode.IsLower(previous) || unicode.IsDigit(previous) || nextLower) {
+				result = append(result, '_')
+			}
+		}
+		result = append(result, unicode.ToLower(r))
+	}
+	return string(result)
+}
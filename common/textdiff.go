@@ -0,0 +1,161 @@
+package common
+
+//
+// Line based diff of two texts, used by '--check' to show the drift between the generated code and the files on disk
+//
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around a change in a hunk
+const diffContext = 3
+
+// diffOp is a line of the edit script, ' ' for an unchanged line, '-' for a removed and '+' for an added line
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the differences between two texts in the unified diff format, empty if they are equal
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	code := fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName)
+	// line numbers (0 based) of the first op in the old and the new text
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+		if op.kind != '+' {
+			fromLine[i+1]++
+		}
+		if op.kind != '-' {
+			toLine[i+1]++
+		}
+	}
+
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// A hunk runs until there are more than two times the context of unchanged lines
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+		end := start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		last := end
+		for last > start && ops[last-1].kind == ' ' {
+			last--
+		}
+		if last += diffContext; last > len(ops) {
+			last = len(ops)
+		}
+
+		code += fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(fromLine[first], fromLine[last]-fromLine[first]), hunkRange(toLine[first], toLine[last]-toLine[first]))
+		for _, op := range ops[first:last] {
+			code += string(op.kind) + op.line
+			if !strings.HasSuffix(op.line, "\n") {
+				code += "\n\\ No newline at end of file\n"
+			}
+		}
+		start = last
+	}
+	return code
+}
+
+// hunkRange returns the line range of a hunk, 'start,count' with a 1 based start (the line before for an empty range)
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits a text in lines, the lines keep their line break
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning the lines a into b (Myers' algorithm)
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// the furthest reaching paths before each step, trace[d][k+d+1] is the x of diagonal k
+	trace := [][]int{}
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		done := false
+		for k := -d; k <= d && !done; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			done = x >= n && y >= m
+		}
+		if done {
+			break
+		}
+	}
+
+	ops := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		previous := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && previous[k-1+d+1] < previous[k+1+d+1]) {
+			prevK = k + 1
+		}
+		prevX := previous[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
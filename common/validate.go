@@ -0,0 +1,586 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity of a validation diagnostic
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (severity Severity) String() string {
+	if severity == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a single problem found in a model document
+type Diagnostic struct {
+	Pos      SourcePos
+	Severity Severity
+	Message  string
+}
+
+func (diag Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", diag.Pos, diag.Severity, diag.Message)
+}
+
+// Diagnostics is the collected result of a validation pass
+type Diagnostics []Diagnostic
+
+// HasErrors returns true if any of the diagnostics is an error (warnings don't count)
+func (diags Diagnostics) HasErrors() bool {
+	for _, diag := range diags {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCount returns the number of error diagnostics
+func (diags Diagnostics) ErrorCount() int {
+	count := 0
+	for _, diag := range diags {
+		if diag.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// A namespace is one or more identifiers separated by '.', like 'shared.common'
+var namespaceRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// Types which are understood by the generators without any type mapping
+var builtinTypes = map[string]bool{
+	"bool": true, "byte": true, "rune": true, "string": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+type validator struct {
+	doc     *XMLDoc
+	diags   Diagnostics
+	defines map[string]*XMLDefine
+	mapped  map[string]bool
+}
+
+// ValidateDocument checks a loaded (and merged) document for problems which would otherwise
+// show up as broken generated code. All problems are collected, the validation does not stop at the first one.
+// Referenced documents are validated as well, each one once.
+func ValidateDocument(doc *XMLDoc) Diagnostics {
+	diags := Diagnostics{}
+	// The package (GO) and namespace (C++) of the generated code are named after the namespace
+	if doc.Namespace == "" {
+		diags = append(diags, Diagnostic{Pos: SourcePos{File: doc.Filename}, Severity: SeverityError, Message: "document has no namespace, it names the package of the generated code"})
+	}
+	return append(diags, validateDocument(doc, make(map[*XMLDoc]bool))...)
+}
+
+func validateDocument(doc *XMLDoc, validated map[*XMLDoc]bool) Diagnostics {
+	validated[doc] = true
+	v := validator{
+		doc:     doc,
+		defines: make(map[string]*XMLDefine),
+		mapped:  make(map[string]bool),
+	}
+
+	if doc.Namespace != "" && !namespaceRegexp.MatchString(doc.Namespace) {
+		v.errorf(SourcePos{File: doc.Filename}, "namespace '%s' is not valid (identifiers separated by '.')", doc.Namespace)
+	}
+	for _, refDoc := range doc.References {
+		if refDoc.Namespace == "" {
+			v.errorf(SourcePos{File: refDoc.Filename}, "referenced document has no namespace, the types can't be referenced")
+		} else if refDoc.Namespace == doc.Namespace {
+			v.errorf(SourcePos{File: refDoc.Filename}, "referenced document has the same namespace '%s' as %s, include it without mode=\"reference\"", refDoc.Namespace, doc.Filename)
+		}
+		if !validated[refDoc] {
+			v.diags = append(v.diags, validateDocument(refDoc, validated)...)
+		}
+	}
+
+	v.validateTypeMappings("dbtypemappings", doc.DBTypeMappings)
+	v.validateTypeMappings("gotypemappings", doc.GOTypeMappings)
+	v.validateTypeMappings("anytypemappings", doc.AnyTypeMappings)
+
+	// Collect defines first, fields may reference defines declared further down
+	for i := range doc.Defines {
+		define := &doc.Defines[i]
+		if define.Name == "" {
+			v.errorf(define.Pos, "define is missing the 'name' attribute")
+			continue
+		}
+		if !identifierRegexp.MatchString(define.Name) {
+			v.errorf(define.Pos, "define name '%s' is not a valid identifier", define.Name)
+		}
+		if previous, ok := v.defines[define.Name]; ok {
+			v.errorf(define.Pos, "duplicate define '%s', previously defined at %s", define.Name, previous.Pos)
+			continue
+		}
+		v.defines[define.Name] = define
+	}
+
+	for i := range doc.Defines {
+		define := &doc.Defines[i]
+		v.validateVersions(define.Pos, fmt.Sprintf("define '%s'", define.Name), define.FromVersion, define.ToVersion)
+		switch define.Type {
+		case "class":
+			v.validateClass(define)
+		case "enum":
+			v.validateEnum(define)
+		case "":
+			v.errorf(define.Pos, "define '%s' is missing the 'type' attribute (class or enum)", define.Name)
+		default:
+			v.errorf(define.Pos, "define '%s' has unknown type '%s' (class or enum)", define.Name, define.Type)
+		}
+	}
+
+	for i := range doc.Defines {
+		define := &doc.Defines[i]
+		if define.Type != "class" {
+			continue
+		}
+		if cycle := doc.valueCycle(define); len(cycle) > 0 {
+			path := []string{}
+			for _, ref := range cycle {
+				path = append(path, ref.String())
+			}
+			v.errorf(define.Pos, "class '%s' contains itself by value (%s), make one of the fields a pointer (ispointer=\"true\") or a list", define.Name, strings.Join(path, " -> "))
+		}
+	}
+
+	if _, err := doc.SortDefinesByDependency(); err != nil {
+		v.warnf(SourcePos{File: doc.Filename}, "%s, foreign key checks are disabled during creation (SET FOREIGN_KEY_CHECKS=0)", err)
+	}
+
+	return v.diags
+}
+
+func (v *validator) errorf(pos SourcePos, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{Pos: pos, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(pos SourcePos, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{Pos: pos, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateTypeMappings(section string, mappings []XMLTypeMapping) {
+	seen := make(map[string]*XMLTypeMapping)
+	for i := range mappings {
+		mapping := &mappings[i]
+		if mapping.FromType == "" || mapping.ToType == "" {
+			v.errorf(mapping.Pos, "%s: mapping requires both 'from' and 'to'", section)
+			continue
+		}
+		v.mapped[mapping.FromType] = true
+
+		verbs := strings.Count(mapping.ToType, "%") - 2*strings.Count(mapping.ToType, "%%")
+		if verbs > 1 {
+			v.errorf(mapping.Pos, "%s: mapping for '%s' has more than one format verb in '%s'", section, mapping.FromType, mapping.ToType)
+		} else if verbs == 1 && !strings.Contains(mapping.ToType, "%d") {
+			v.errorf(mapping.Pos, "%s: mapping for '%s' must use '%%d' for the field size in '%s'", section, mapping.FromType, mapping.ToType)
+		} else if verbs == 1 && mapping.FieldSize == 0 {
+			v.errorf(mapping.Pos, "%s: mapping for '%s' uses a field size but declares no 'fieldsize'", section, mapping.FromType)
+		} else if verbs == 0 && mapping.FieldSize != 0 {
+			v.errorf(mapping.Pos, "%s: mapping for '%s' declares 'fieldsize' but '%s' has no '%%d'", section, mapping.FromType, mapping.ToType)
+		}
+
+		key := mapping.Lang + ":" + mapping.FromType
+		if previous, ok := seen[key]; ok {
+			v.warnf(mapping.Pos, "%s: duplicate mapping for '%s', the mapping at %s is used", section, mapping.FromType, previous.Pos)
+			continue
+		}
+		seen[key] = mapping
+	}
+}
+
+func (v *validator) isKnownType(typeName string) bool {
+	if builtinTypes[typeName] || v.mapped[typeName] {
+		return true
+	}
+	_, ok := v.defines[typeName]
+	return ok || len(v.doc.FindReferencedDefines(typeName)) > 0
+}
+
+// validateReferencedType checks the type of a field declared by a referenced document, it must be declared by one document only
+func (v *validator) validateReferencedType(define *XMLDefine, field *XMLDataTypeField) {
+	if _, ok := v.defines[field.Type]; ok {
+		return
+	}
+	refDocs := v.doc.FindReferencedDefines(field.Type)
+	if len(refDocs) > 1 {
+		namespaces := []string{}
+		for _, refDoc := range refDocs {
+			namespaces = append(namespaces, refDoc.Namespace)
+		}
+		v.errorf(field.Pos, "type '%s' of field '%s::%s' is ambiguous, it is declared in the referenced namespaces %s", field.Type, define.Name, field.Name, strings.Join(namespaces, ", "))
+	}
+}
+
+func (v *validator) validateClass(define *XMLDefine) {
+	v.validateInheritance(define)
+
+	if len(define.Fields) == 0 {
+		if define.Inherits == "" {
+			v.errorf(define.Pos, "class '%s' has no fields", define.Name)
+		}
+		return
+	}
+
+	names := make(map[string]*XMLDataTypeField)
+	columns := make(map[string]*XMLDataTypeField)
+	persistedFields := 0
+	for i := range define.Fields {
+		field := &define.Fields[i]
+		if field.Name == "" {
+			v.errorf(field.Pos, "field in class '%s' is missing the 'name' attribute", define.Name)
+			continue
+		}
+		if !identifierRegexp.MatchString(field.Name) {
+			v.errorf(field.Pos, "field name '%s::%s' is not a valid identifier", define.Name, field.Name)
+		}
+		if previous, ok := names[field.Name]; ok {
+			v.errorf(field.Pos, "duplicate field '%s::%s', previously declared at %s", define.Name, field.Name, previous.Pos)
+			continue
+		}
+		names[field.Name] = field
+		if define.Inherits != "" {
+			if inherited := v.doc.FindClassField(v.doc.FindDefine(define.Inherits), field.Name); inherited != nil && inherited != field {
+				v.errorf(field.Pos, "field '%s::%s' hides the inherited field declared at %s", define.Name, field.Name, inherited.Pos)
+			}
+		}
+
+		if field.Type == "" {
+			v.errorf(field.Pos, "field '%s::%s' is missing the 'type' attribute", define.Name, field.Name)
+		} else if !v.isKnownType(field.Type) {
+			v.errorf(field.Pos, "field '%s::%s' has unresolved type '%s'", define.Name, field.Name, field.Type)
+		} else {
+			v.validateReferencedType(define, field)
+		}
+
+		if !field.IsRemoved() {
+			// A removed relation is only dropped, its other side may be removed as well
+			v.validateRelation(define, field)
+		}
+		v.validateConstraints(define, field)
+		v.validateRemoval(define, field)
+
+		if define.SkipPersistance || !field.IsPersisted() {
+			continue
+		}
+		persistedFields++
+
+		column := strings.ToLower(field.Name)
+		if previous, ok := columns[column]; ok {
+			v.errorf(field.Pos, "field '%s::%s' maps to the same column as '%s'", define.Name, field.Name, previous.Name)
+		}
+		columns[column] = field
+
+		// Classes and enums (stored as their value, like 'int(11)') need a DB type mapping to be a column
+		if userDefine, ok := v.defines[field.Type]; ok && !v.hasDBMapping(field.Type) {
+			v.warnf(field.Pos, "field '%s::%s' of %s type '%s' has no DB type mapping", define.Name, field.Name, userDefine.Type, field.Type)
+		} else if refDocs := v.doc.FindReferencedDefines(field.Type); !ok && len(refDocs) == 1 && !v.hasDBMapping(field.Type) {
+			if field.GetTypeMappingLang(refDocs[0].DBTypeMappings, "") == nil {
+				v.warnf(field.Pos, "field '%s::%s' of %s type '%s' (namespace '%s') has no DB type mapping", define.Name, field.Name, refDocs[0].FindDefine(field.Type).Type, field.Type, refDocs[0].Namespace)
+			}
+		}
+	}
+
+	if define.SkipPersistance || define.IsRemoved() {
+		return
+	}
+
+	v.validateIndexes(define)
+
+	keys := v.doc.ClassKeys(define)
+	for _, key := range keys {
+		if key.IsRemoved() {
+			// reported by validateRemoval
+			continue
+		}
+		if key.Nullable {
+			v.errorf(key.Pos, "class '%s' can't use nullable field '%s' as primary key", define.Name, key.Name)
+		}
+		if !key.IsPersisted() {
+			v.errorf(key.Pos, "class '%s' can't use field '%s' as primary key, it is not persisted (set nopersist=\"true\" on the class if it shouldn't be persisted)", define.Name, key.Name)
+		} else if key.IsList {
+			v.errorf(key.Pos, "class '%s' can't use list field '%s' as primary key", define.Name, key.Name)
+		}
+	}
+	if persistedFields <= len(keys) && define.Inherits == "" {
+		v.warnf(define.Pos, "class '%s' has no persisted fields besides the primary key, only Retrieve and Delete are generated (set nopersist=\"true\" on the class)", define.Name)
+	}
+}
+
+// validateInheritance checks 'inherits' and 'inheritance' of a class, a joined class must inherit a persisted class
+// and can't declare its own primary key as the key is shared with the parent table
+func (v *validator) validateInheritance(define *XMLDefine) {
+	switch define.Inheritance {
+	case "", InheritanceFlat, InheritanceJoined:
+	default:
+		v.errorf(define.Pos, "class '%s' has unknown inheritance '%s' (flat or joined)", define.Name, define.Inheritance)
+	}
+	if define.Inherits == "" {
+		if define.Inheritance != "" {
+			v.warnf(define.Pos, "class '%s' has 'inheritance' but doesn't inherit a class", define.Name)
+		}
+		return
+	}
+
+	parent, ok := v.defines[define.Inherits]
+	if ok && parent.IsRemoved() && !define.IsRemoved() {
+		v.errorf(define.Pos, "class '%s' inherits the removed class '%s'", define.Name, define.Inherits)
+	}
+	if refDocs := v.doc.FindReferencedDefines(define.Inherits); !ok && len(refDocs) > 0 {
+		v.errorf(define.Pos, "class '%s' can't inherit '%s' of the referenced namespace '%s', include the document without mode=\"reference\"", define.Name, define.Inherits, refDocs[0].Namespace)
+		return
+	} else if !ok {
+		v.errorf(define.Pos, "class '%s' inherits unknown type '%s'", define.Name, define.Inherits)
+		return
+	} else if parent.Type != "class" {
+		v.errorf(define.Pos, "class '%s' inherits '%s' which is not a class", define.Name, define.Inherits)
+		return
+	}
+	chain := v.doc.InheritanceChain(define)
+	if last := chain[len(chain)-1]; last.Inherits != "" && v.doc.FindDefine(last.Inherits) != nil {
+		v.errorf(define.Pos, "class '%s' is part of an inheritance cycle", define.Name)
+		return
+	}
+
+	if define.SkipPersistance {
+		return
+	}
+	if define.IsJoined() && parent.SkipPersistance {
+		v.errorf(define.Pos, "class '%s' has inheritance=\"joined\" but '%s' is not persisted", define.Name, parent.Name)
+	}
+	for _, current := range chain[:len(chain)-1] {
+		if !current.IsJoined() {
+			continue
+		}
+		for i := range define.Fields {
+			if define.Fields[i].PrimaryKey {
+				v.errorf(define.Fields[i].Pos, "class '%s' shares the primary key of '%s' (inheritance=\"joined\"), field '%s' can't be a primary key", define.Name, current.Inherits, define.Fields[i].Name)
+			}
+		}
+		break
+	}
+}
+
+func (v *validator) validateRelation(define *XMLDefine, field *XMLDataTypeField) {
+	if _, err := field.OnDeleteAction(); err != nil {
+		v.errorf(field.Pos, "field '%s::%s' %s", define.Name, field.Name, err)
+	} else if action, _ := field.OnDeleteAction(); action == "SET NULL" && !field.IsNullable() {
+		v.errorf(field.Pos, "field '%s::%s' has ondelete=\"%s\" but is not nullable (nullable=\"true\")", define.Name, field.Name, field.OnDelete)
+	} else if field.OnDelete != "" && field.References == "" {
+		v.warnf(field.Pos, "field '%s::%s' has 'ondelete' but no 'references'", define.Name, field.Name)
+	}
+
+	if field.References != "" {
+		if field.IsList {
+			v.errorf(field.Pos, "list field '%s::%s' can't have 'references', use relation=\"onetomany\" or relation=\"manytomany\"", define.Name, field.Name)
+		}
+		refDefine, refField, err := v.doc.ResolveReference(field)
+		if err != nil {
+			v.errorf(field.Pos, "field '%s::%s' %s", define.Name, field.Name, err)
+		} else if keys := v.doc.ClassKeys(refDefine); len(keys) != 1 || keys[0].Name != refField.Name {
+			v.errorf(field.Pos, "field '%s::%s' must reference the single field primary key of '%s'", define.Name, field.Name, refDefine.Name)
+		} else if field.TypeMapping(v.doc.DBTypeMappings) != refField.TypeMapping(v.doc.DBTypeMappings) {
+			v.errorf(field.Pos, "field '%s::%s' has DB type '%s' but references '%s.%s' of DB type '%s'", define.Name, field.Name,
+				field.TypeMapping(v.doc.DBTypeMappings), refDefine.Name, refField.Name, refField.TypeMapping(v.doc.DBTypeMappings))
+		}
+	}
+
+	switch field.Relation {
+	case "":
+		if field.MappedBy != "" {
+			v.warnf(field.Pos, "field '%s::%s' has 'mappedby' but no relation=\"onetomany\"", define.Name, field.Name)
+		}
+	case RelationOneToMany, RelationManyToMany:
+		if !field.IsList {
+			v.errorf(field.Pos, "relation field '%s::%s' must be a list (islist=\"true\")", define.Name, field.Name)
+		}
+		if field.References != "" || field.PrimaryKey || field.Nullable {
+			v.errorf(field.Pos, "relation field '%s::%s' is not a column, it can't have 'references', 'primarykey' or 'nullable'", define.Name, field.Name)
+		}
+		var err error
+		if field.Relation == RelationOneToMany {
+			_, _, err = v.doc.ResolveOneToMany(define, field)
+		} else {
+			_, _, _, err = v.doc.ResolveManyToMany(define, field)
+		}
+		if err != nil {
+			v.errorf(field.Pos, "field '%s::%s': %s", define.Name, field.Name, err)
+		}
+	default:
+		v.errorf(field.Pos, "field '%s::%s' has unknown relation '%s' (onetomany or manytomany)", define.Name, field.Name, field.Relation)
+	}
+}
+
+func (v *validator) validateConstraints(define *XMLDefine, field *XMLDataTypeField) {
+	if !field.HasConstraints() {
+		return
+	}
+	goType := field.TypeMapping(v.doc.GOTypeMappings)
+	isString := goType == "string" && !field.IsList
+
+	for _, limit := range []struct{ name, value string }{{"min", field.Min}, {"max", field.Max}} {
+		if limit.value == "" {
+			continue
+		}
+		if !IsNumericGoType(goType) || field.IsList {
+			v.errorf(field.Pos, "field '%s::%s' has '%s' but is not a number", define.Name, field.Name, limit.name)
+		} else if _, err := strconv.ParseFloat(limit.value, 64); err != nil {
+			v.errorf(field.Pos, "field '%s::%s' has invalid %s '%s'", define.Name, field.Name, limit.name, limit.value)
+		} else if IsIntegerGoType(goType) && !IsIntegerLimit(goType, limit.value) {
+			v.errorf(field.Pos, "field '%s::%s' has %s '%s', not an integer in the range of %s", define.Name, field.Name, limit.name, limit.value, goType)
+		}
+	}
+	if field.Min != "" && field.Max != "" {
+		min, errMin := strconv.ParseFloat(field.Min, 64)
+		max, errMax := strconv.ParseFloat(field.Max, 64)
+		if errMin == nil && errMax == nil && min > max {
+			v.errorf(field.Pos, "field '%s::%s' has min larger than max", define.Name, field.Name)
+		}
+	}
+
+	if (field.MinLen != 0 || field.MaxLen != 0) && !isString && !field.IsList {
+		v.errorf(field.Pos, "field '%s::%s' has 'minlen'/'maxlen' but is not a string or list", define.Name, field.Name)
+	}
+	if field.MinLen < 0 || field.MaxLen < 0 {
+		v.errorf(field.Pos, "field '%s::%s' has negative 'minlen'/'maxlen'", define.Name, field.Name)
+	}
+	if maxLen := field.EffectiveMaxLen(v.doc); maxLen != 0 && field.MinLen > maxLen {
+		v.errorf(field.Pos, "field '%s::%s' has minlen larger than the max length %d", define.Name, field.Name, maxLen)
+	}
+
+	if field.Pattern != "" {
+		if !isString {
+			v.errorf(field.Pos, "field '%s::%s' has 'pattern' but is not a string", define.Name, field.Name)
+		} else if _, err := regexp.Compile(field.Pattern); err != nil {
+			v.errorf(field.Pos, "field '%s::%s' has invalid pattern: %s", define.Name, field.Name, err)
+		}
+	}
+
+	if field.Required && goType == "bool" && !field.IsList && !field.IsPointer && !field.IsNullable() {
+		v.warnf(field.Pos, "field '%s::%s' is a bool, 'required' has no effect", define.Name, field.Name)
+	}
+}
+
+func (v *validator) validateIndexes(define *XMLDefine) {
+	names := make(map[string]*XMLIndex)
+	for _, unique := range []bool{false, true} {
+		indexes := define.Indexes
+		if unique {
+			indexes = define.Uniques
+		}
+		for i := range indexes {
+			index := &indexes[i]
+			columns, err := index.Columns()
+			if err != nil {
+				v.errorf(index.Pos, "class '%s': %s", define.Name, err)
+				continue
+			}
+			name := index.IndexName(define, unique)
+			if previous, ok := names[name]; ok {
+				v.errorf(index.Pos, "class '%s' has duplicate index '%s', previously declared at %s", define.Name, name, previous.Pos)
+			}
+			names[name] = index
+			for _, column := range columns {
+				field := define.FindField(column.Field)
+				if field == nil {
+					v.errorf(index.Pos, "index '%s' refers to unknown field '%s::%s'", name, define.Name, column.Field)
+				} else if !field.IsPersisted() || field.IsList {
+					v.errorf(index.Pos, "index '%s' can't use field '%s::%s', it is not a persisted column", name, define.Name, column.Field)
+				}
+			}
+		}
+	}
+}
+
+// validateVersions checks the version range of a define or field, the item must be removed after it was added
+func (v *validator) validateVersions(pos SourcePos, item string, fromVersion int, toVersion int) {
+	if fromVersion < 0 || toVersion < 0 {
+		v.errorf(pos, "%s has a negative version", item)
+	} else if toVersion > 0 && toVersion <= fromVersion {
+		v.errorf(pos, "%s has toversion %d which is not after fromversion %d", item, toVersion, fromVersion)
+	}
+}
+
+// validateRemoval checks that a field still in use doesn't depend on removed items and that no key field is removed
+func (v *validator) validateRemoval(define *XMLDefine, field *XMLDataTypeField) {
+	v.validateVersions(field.Pos, fmt.Sprintf("field '%s::%s'", define.Name, field.Name), field.FromVersion, field.ToVersion)
+	if field.IsRemoved() {
+		if define.IsRemoved() || define.SkipPersistance {
+			return
+		}
+		for _, key := range v.doc.ClassKeys(define) {
+			if key == field {
+				v.errorf(field.Pos, "field '%s::%s' is part of the primary key and can't be removed", define.Name, field.Name)
+			}
+		}
+		return
+	}
+	if define.IsRemoved() {
+		return
+	}
+	if userDefine, ok := v.defines[field.Type]; ok && userDefine.IsRemoved() {
+		v.errorf(field.Pos, "field '%s::%s' uses the removed type '%s'", define.Name, field.Name, field.Type)
+	}
+	if field.References != "" {
+		if refDefine, refField, err := v.doc.ResolveReference(field); err == nil && (refDefine.IsRemoved() || refField.IsRemoved()) {
+			v.errorf(field.Pos, "field '%s::%s' references the removed '%s'", define.Name, field.Name, field.References)
+		}
+	}
+}
+
+func (v *validator) hasDBMapping(typeName string) bool {
+	for _, mapping := range v.doc.DBTypeMappings {
+		if mapping.FromType == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateEnum(define *XMLDefine) {
+	if len(define.Ints) == 0 {
+		if len(define.Fields) > 0 {
+			v.errorf(define.Pos, "enum '%s' declares <field> elements, enum values are declared with <int>", define.Name)
+		} else {
+			v.errorf(define.Pos, "enum '%s' has no values", define.Name)
+		}
+		return
+	}
+
+	names := make(map[string]*XMLDataTypeField)
+	values := make(map[int]*XMLDataTypeField)
+	for i := range define.Ints {
+		value := &define.Ints[i]
+		if !identifierRegexp.MatchString(value.Name) {
+			v.errorf(value.Pos, "enum value name '%s::%s' is not a valid identifier", define.Name, value.Name)
+		}
+		if previous, ok := names[value.Name]; ok {
+			v.errorf(value.Pos, "duplicate enum value name '%s::%s', previously declared at %s", define.Name, value.Name, previous.Pos)
+		}
+		names[value.Name] = value
+		if value.IsRemoved() {
+			v.warnf(value.Pos, "enum value '%s::%s' has 'removed' or 'toversion', enum values can't be removed (ignored)", define.Name, value.Name)
+		}
+		if previous, ok := values[value.Value]; ok {
+			v.errorf(value.Pos, "enum values '%s::%s' and '%s' share the value %d", define.Name, value.Name, previous.Name, value.Value)
+		}
+		values[value.Value] = value
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+//
+// Model versions, 'fromversion' is the version adding a define or field and 'toversion' the version removing it.
+// An upgrade script (-f) adds what was added and drops what was removed in or after the given version.
+//
+
+// IsRemoved returns true if the field is removed ('removed' or 'toversion'), it is no longer persisted
+func (field *XMLDataTypeField) IsRemoved() bool {
+	return field.Removed || field.ToVersion > 0
+}
+
+// IsRemoved returns true if the define is removed ('removed' or 'toversion'), nothing is generated for it
+func (define *XMLDefine) IsRemoved() bool {
+	return define.Removed || define.ToVersion > 0
+}
+
+// IsAddedSince returns true if the define was added in or after the version, an upgrade creates the table
+func (define *XMLDefine) IsAddedSince(version int) bool {
+	return define.FromVersion > 0 && define.FromVersion >= version
+}
+
+// LatestVersion returns the highest version used in the document, the version of the model
+func (doc *XMLDoc) LatestVersion() int {
+	latest := 0
+	update := func(versions ...int) {
+		for _, version := range versions {
+			if version > latest {
+				latest = version
+			}
+		}
+	}
+	for i := range doc.Defines {
+		define := &doc.Defines[i]
+		update(define.FromVersion, define.ToVersion)
+		for j := range define.Fields {
+			update(define.Fields[j].FromVersion, define.Fields[j].ToVersion)
+		}
+		for j := range define.Indexes {
+			update(define.Indexes[j].FromVersion)
+		}
+		for j := range define.Uniques {
+			update(define.Uniques[j].FromVersion)
+		}
+	}
+	return latest
+}
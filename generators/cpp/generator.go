@@ -3,8 +3,8 @@ package cpp
 import "modelgenerator/common"
 
 type CodeGenerator struct {
-	Methods []common.AccessMethod
-	Imports []common.XMLImport
+	Imports   []common.XMLImport
+	templates *common.Templates
 }
 
 type CppLangGenerators struct{}
@@ -14,6 +14,19 @@ func CreateCppLanguage() common.Language {
 	return (common.Language)(&cpplang)
 }
 
+func init() {
+	common.RegisterLanguage(common.LanguageInfo{
+		Name:        "cpp",
+		Aliases:     []string{"c++"},
+		Description: "C++ classes in a header file",
+		Create:      CreateCppLanguage,
+		Options: []common.LanguageOption{
+			{Name: "marshalling", Help: "generate the marshalling code (same as -M)",
+				Apply: common.BoolOption(func(options *common.Options, value bool) { options.CPPJson = value })},
+		},
+	})
+}
+
 func (lang *CppLangGenerators) GetModelGenerator() common.Generator {
 	return createCppLangGenerator()
 }
@@ -0,0 +1,65 @@
+package cpp
+
+//
+// Templates of the C++ generator (templates/*.tmpl)
+//
+
+import (
+	"embed"
+	"fmt"
+	"modelgenerator/common"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templateFile is the data of the 'header' and 'footer' templates
+type templateFile struct {
+	Options    *common.Options
+	Optional   bool     // a field is std::optional
+	Includes   []string // module paths of the referenced documents
+	Namespaces []string
+	JSONBase   string
+}
+
+// templateType is the data of the templates generating code for a define, '.Type' is the define itself
+type templateType struct {
+	*common.Type
+	Options  *common.Options
+	JSONBase string
+}
+
+// templateField is a member variable of a class
+type templateField struct {
+	*common.Field
+	Prefix string
+}
+
+// loadTemplates returns the C++ templates, overridden by the templates in the template directory of the options
+func loadTemplates(options *common.Options) (*common.Templates, error) {
+	templates, err := common.LoadTemplates(common.LangCpp, templateFiles, options, templateFuncs())
+	if err != nil {
+		return nil, fmt.Errorf("can't load the C++ templates: %w", err)
+	}
+	return templates, nil
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"doc":      docComment,
+		"fieldDoc": fieldDocumentation,
+		"optional": isFieldOptional,
+		"baseCall": baseCall,
+		"encode":   encode,
+		"decode":   decode,
+		// fields returns the member variables of a class
+		"fields": func(define templateType) []templateField {
+			fields := []templateField{}
+			for _, field := range define.Fields {
+				fields = append(fields, templateField{Field: field, Prefix: memberPrefix(define.Type, define.Options)})
+			}
+			return fields
+		},
+	}
+}
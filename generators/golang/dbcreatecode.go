@@ -11,42 +11,101 @@ import (
 	"fmt"
 	"log"
 	"modelgenerator/common"
+	"regexp"
 	"strings"
 )
 
-func (generator *DBGenerator) GenerateCode(doc common.XMLDoc, options *common.Options) string {
+func (generator *DBGenerator) GenerateCode(model *common.Model, options *common.Options) (string, error) {
 	code := ""
 
-	// className string, source string, splitInFiles bool, converters bool, verbose int, outputDir string
+	code += generateDBCreateHeader(model.Doc, options.Filename)
 
-	if options.SplitInFiles != true {
-		code += generateDBCreateHeader(doc, options.Filename)
-		// generate code for all defines
-		for i := 0; i < len(doc.Defines); i++ {
-			if doc.Defines[i].SkipPersistance == true {
-				continue
-			}
-			if strings.Compare(options.PersistenceClass, "-") != 0 {
-				for j := 0; j < len(options.AllPersistenceClasses); j++ {
-					options.PersistenceClass = options.AllPersistenceClasses[j]
-					code += generateDBCreateCodeForDefine(&doc.Defines[i], options)
+	// Referenced tables must be created before the tables referencing them
+	defines, err := model.SortTypesByDependency()
+	if err != nil {
+		log.Printf("!WARNING!: %s, disabling foreign key checks during creation\n", err)
+		code += fmt.Sprintf("SET FOREIGN_KEY_CHECKS=0;\n")
+	}
+	defines = persistedDefines(defines, options)
 
-					//code += doc.Defines[i].generatePersistenceCode(options.AllPersistenceClasses[j], options.Converters)
-				}
-			} else {
-				code += generateDBCreateCodeForDefine(&doc.Defines[i], options)
-				//code += doc.Defines[i].generatePersistenceCode(options.PersistenceClass, options.Converters)
-			}
+	if options.GenerateDropStatement == true && options.IsUpgrade != true {
+		code += generateDBDropCode(defines, options)
+	}
+	for _, define := range defines {
+		code += generateDBCreateCodeForDefine(define, options)
+	}
+	for _, define := range defines {
+		code += generateDBCreateCodeForJoinTables(define, options)
+	}
+	if options.IsUpgrade {
+		code += generateDBDropRemovedCode(model, options)
+	}
+	if err != nil {
+		code += fmt.Sprintf("SET FOREIGN_KEY_CHECKS=1;\n")
+	}
+
+	return code, nil
+}
 
+// GenerateFiles splits the script in a file per table named after the table, numbered by the dependency level of the table
+// so the files run in name order ('001_nagini_se_user.sql', '002_nagini_se_order.sql', ...). A table gets the same number
+// as long as the tables it references keep theirs, a join table comes after the tables of both classes. The drop
+// statements (-d) are in '000_drop.sql' and the drops of removed tables of an upgrade (-f) in '999_drop_removed.sql'.
+func (generator *DBGenerator) GenerateFiles(model *common.Model, options *common.Options) ([]common.GeneratedFile, error) {
+	defines, err := model.SortTypesByDependency()
+	if err != nil {
+		log.Printf("!WARNING!: %s, disabling foreign key checks during creation\n", err)
+	}
+	defines = persistedDefines(defines, options)
+
+	files := []common.GeneratedFile{}
+	addFile := func(number int, name string, code string) {
+		if err != nil {
+			code = "SET FOREIGN_KEY_CHECKS=0;\n" + code + "SET FOREIGN_KEY_CHECKS=1;\n"
 		}
-	} else {
-		log.Panicln("SPLIT IN FILES NOT SUPPORTED!!!!")
+		files = append(files, common.GeneratedFile{
+			Name: fmt.Sprintf("%03d_%s.sql", number, name),
+			Code: generateDBSourceHeader(options.Filename) + generateDBCreateHeader(model.Doc, options.Filename) + code,
+		})
 	}
 
-	return code
+	if options.GenerateDropStatement == true && options.IsUpgrade != true {
+		addFile(0, "drop", generateDBDropCode(defines, options))
+	}
+	levels := model.Doc.DependencyLevels()
+	for _, define := range defines {
+		if define.Type == "class" {
+			addFile(levels[define.Name], getDBTableName(define, options), generateDBCreateCodeForDefine(define, options))
+		}
+	}
+	for _, define := range defines {
+		for _, field := range joinTableFields(define) {
+			level := levels[define.Name]
+			if field.Related != nil && levels[field.Related.Target.Name] > level {
+				level = levels[field.Related.Target.Name]
+			}
+			addFile(level+1, getDBJoinTableName(define, field, options), generateDBCreateCodeForJoinTable(define, field, options))
+		}
+	}
+	if options.IsUpgrade {
+		if code := generateDBDropRemovedCode(model, options); code != "" {
+			addFile(999, "drop_removed", code)
+		}
+	}
+	return files, nil
 }
 
-func generateDBCreateHeader(doc common.XMLDoc, source string) string {
+// IsGeneratedFile returns true for the files of the DB script
+func (generator *DBGenerator) IsGeneratedFile(name string) bool {
+	return strings.HasSuffix(name, ".sql")
+}
+
+// generateDBSourceHeader names the model in a file of a split script, stale files are recognized by it
+func generateDBSourceHeader(source string) string {
+	return fmt.Sprintf("--\n-- this code is generated by the modelgenerator\n-- data model source = %s\n--\n", source)
+}
+
+func generateDBCreateHeader(doc *common.XMLDoc, source string) string {
 	code := ""
 	if len(doc.DBControl.DBName) > 0 {
 		code = fmt.Sprintf("USE `%s`;\n", doc.DBControl.DBName)
@@ -57,19 +116,218 @@ func generateDBCreateHeader(doc common.XMLDoc, source string) string {
 	return code
 }
 
-func getDBTableName(define *common.XMLDefine, options *common.Options) string {
+func getDBTableName(define *common.Type, options *common.Options) string {
 	return fmt.Sprintf("%s%s", options.DBTablePrefix, strings.ToLower(define.Name))
 }
 
-func generateDBCreateCodeForDefine(define *common.XMLDefine, options *common.Options) string {
-	//options.PersistenceClass, options.Converters)
-	// Check if class name matches - perhaps use regexp here..
-	if strings.Compare(options.PersistenceClass, "-") != 0 {
-		if strings.Compare(options.PersistenceClass, define.Name) != 0 {
-			return ""
+// getDBJoinTableName returns the name of the join table for a many to many relation field
+func getDBJoinTableName(define *common.Type, field *common.Field, options *common.Options) string {
+	return fmt.Sprintf("%s%s_%s", options.DBTablePrefix, strings.ToLower(define.Name), strings.ToLower(field.Name))
+}
+
+// persistedDefines filters out the defines which should not be in the DB
+func persistedDefines(defines []*common.Type, options *common.Options) []*common.Type {
+	result := []*common.Type{}
+	for _, define := range defines {
+		if define.SkipPersistance == true {
+			continue
+		}
+		if !options.IsPersistenceClass(define.Name) {
+			continue
+		}
+		result = append(result, define)
+	}
+	return result
+}
+
+// generateDBDropCode drops all tables in reverse creation order (join tables first) so no foreign key is violated
+func generateDBDropCode(defines []*common.Type, options *common.Options) string {
+	code := "\n"
+	for i := len(defines) - 1; i >= 0; i-- {
+		define := defines[i]
+		if define.Type != "class" {
+			continue
+		}
+		for _, field := range define.Fields {
+			if field.Relation == common.RelationManyToMany && !field.IsRemoved() {
+				code += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", getDBJoinTableName(define, field, options))
+			}
 		}
 	}
+	for i := len(defines) - 1; i >= 0; i-- {
+		if defines[i].Type == "class" {
+			code += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", getDBTableName(defines[i], options))
+		}
+	}
+	return code
+}
 
+// generateDBDropRemovedCode drops the tables of the classes removed in or after the upgraded version
+func generateDBDropRemovedCode(model *common.Model, options *common.Options) string {
+	removed := []*common.Type{}
+	for _, define := range persistedDefines(model.RemovedTypes, options) {
+		if define.ToVersion >= options.FromVersion {
+			removed = append(removed, define)
+		}
+	}
+	if len(removed) == 0 {
+		return ""
+	}
+	return generateDBDropCode(removed, options)
+}
+
+// generateDBDropColumnsCode drops the columns of the fields removed in or after the upgraded version and the join tables
+// of removed relations. A flat table drops the removed columns of the classes it inherits from as well.
+func generateDBDropColumnsCode(define *common.Type, table *common.Table, options *common.Options) string {
+	code := ""
+	tableName := getDBTableName(define, options)
+	for _, source := range table.Sources {
+		for _, field := range source.RemovedFields {
+			if field.ToVersion < options.FromVersion || field.SkipPersistance {
+				continue
+			}
+			switch {
+			case field.Relation == common.RelationManyToMany && source == define:
+				code += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", getDBJoinTableName(define, field, options))
+			case field.Relation == "":
+				if field.References != "" {
+					code += fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `fk_%s_%s`;\n", tableName, tableName, field.GetDBColumnName(options))
+				}
+				code += fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`;\n", tableName, field.GetDBColumnName(options))
+			}
+		}
+	}
+	return code
+}
+
+// generateDBIndexColumns returns the column list of an index, like '`lastname`,`path`(64)'
+func generateDBIndexColumns(define *common.Type, index *common.XMLIndex, options *common.Options) string {
+	columns, err := index.Columns()
+	if err != nil {
+		log.Printf("!WARNING!: index on '%s' %s\n", define.Name, err)
+		return ""
+	}
+	code := []string{}
+	for _, column := range columns {
+		columnName := strings.ToLower(column.Field)
+		if field := define.FindField(column.Field); field != nil {
+			columnName = field.GetDBColumnName(options)
+		}
+		if column.Prefix > 0 {
+			code = append(code, fmt.Sprintf("`%s`(%d)", columnName, column.Prefix))
+		} else {
+			code = append(code, fmt.Sprintf("`%s`", columnName))
+		}
+	}
+	return strings.Join(code, ",")
+}
+
+// generateDBForeignKey returns the constraint for a field with 'references'
+func generateDBForeignKey(tableName string, column string, refDefine *common.Type, refField *common.Field, onDelete string, options *common.Options) string {
+	code := fmt.Sprintf("CONSTRAINT `fk_%s_%s` FOREIGN KEY (`%s`) REFERENCES `%s` (`%s`)",
+		tableName,
+		column,
+		column,
+		getDBTableName(refDefine, options),
+		refField.GetDBColumnName(options))
+	if onDelete != "" {
+		code += fmt.Sprintf(" ON DELETE %s", onDelete)
+	}
+	return code
+}
+
+// generateDBForeignKeysForClass returns the foreign key constraints for all referencing fields in the table of the class,
+// a joined table also references the parent table with its key
+func generateDBForeignKeysForClass(define *common.Type, options *common.Options, upgradeOnly bool) []string {
+	constraints := []string{}
+	table := define.Table()
+	if table.Joined && !upgradeOnly {
+		constraints = append(constraints, generateDBParentForeignKey(define, options))
+	}
+	for _, field := range table.DeclaredFields() {
+		if field.References == "" || !field.IsPersisted() {
+			continue
+		}
+		if upgradeOnly && field.FromVersion < options.FromVersion {
+			continue
+		}
+		if field.Referenced == nil {
+			log.Printf("!WARNING!: '%s' %s\n", field, field.ResolveError)
+			continue
+		}
+		onDelete, _ := field.OnDeleteAction()
+		constraints = append(constraints, generateDBForeignKey(getDBTableName(define, options), field.GetDBColumnName(options), field.Referenced.Owner, field.Referenced, onDelete, options))
+	}
+	return constraints
+}
+
+// generateDBParentForeignKey returns the constraint of a joined table on the parent table, rows are deleted with the parent row
+func generateDBParentForeignKey(define *common.Type, options *common.Options) string {
+	tables := define.Tables()
+	parentTable := tables[len(tables)-2]
+	columns := []string{}
+	names := []string{}
+	for _, key := range parentTable.Keys {
+		columns = append(columns, fmt.Sprintf("`%s`", key.GetDBColumnName(options)))
+		names = append(names, key.GetDBColumnName(options))
+	}
+	return fmt.Sprintf("CONSTRAINT `fk_%s_%s` FOREIGN KEY (%s) REFERENCES `%s` (%s) ON DELETE CASCADE",
+		getDBTableName(define, options),
+		strings.Join(names, "_"),
+		strings.Join(columns, ","),
+		getDBTableName(parentTable.Type, options),
+		strings.Join(columns, ","))
+}
+
+// generateDBCreateCodeForJoinTables creates the join tables for the many to many relations of a class
+func generateDBCreateCodeForJoinTables(define *common.Type, options *common.Options) string {
+	code := ""
+	for _, field := range joinTableFields(define) {
+		code += generateDBCreateCodeForJoinTable(define, field, options)
+	}
+	return code
+}
+
+// joinTableFields returns the many to many relation fields of a class, each has a join table
+func joinTableFields(define *common.Type) []*common.Field {
+	fields := []*common.Field{}
+	if define.Type != "class" {
+		return fields
+	}
+	for _, field := range define.Fields {
+		if field.Relation == common.RelationManyToMany && !field.IsRemoved() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// generateDBCreateCodeForJoinTable creates the join table of a many to many relation, existing tables are kept on upgrade
+func generateDBCreateCodeForJoinTable(define *common.Type, field *common.Field, options *common.Options) string {
+	code := ""
+	if options.IsUpgrade && field.FromVersion < options.FromVersion && !define.IsAddedSince(options.FromVersion) {
+		return code
+	}
+	if field.Related == nil {
+		log.Printf("!WARNING!: '%s' %s\n", field, field.ResolveError)
+		return code
+	}
+	parentKey, child, childKey := field.Related.OwnerKey, field.Related.Target, field.Related.TargetKey
+	tableName := getDBJoinTableName(define, field, options)
+	parentColumn, childColumn := field.Related.OwnerColumn, field.Related.TargetColumn
+
+	code += "\n"
+	code += fmt.Sprintf("CREATE TABLE `%s` (\n", tableName)
+	code += fmt.Sprintf("  `%s` %s NOT NULL,\n", parentColumn, parentKey.MappedType(common.LangDB))
+	code += fmt.Sprintf("  `%s` %s NOT NULL,\n", childColumn, childKey.MappedType(common.LangDB))
+	code += fmt.Sprintf("  PRIMARY KEY(`%s`,`%s`),\n", parentColumn, childColumn)
+	code += fmt.Sprintf("  %s,\n", generateDBForeignKey(tableName, parentColumn, define, parentKey, "CASCADE", options))
+	code += fmt.Sprintf("  %s\n", generateDBForeignKey(tableName, childColumn, child, childKey, "CASCADE", options))
+	code += fmt.Sprintf(") ENGINE=InnoDB DEFAULT CHARSET=utf8;\n")
+	return code
+}
+
+func generateDBCreateCodeForDefine(define *common.Type, options *common.Options) string {
 	if options.Verbose > 0 {
 		log.Printf("Generating DB Create Statements for class: %s\n", define.Name)
 	}
@@ -91,18 +349,17 @@ func generateDBCreateCodeForDefine(define *common.XMLDefine, options *common.Opt
 	return code
 }
 
-func generateDBCreateCodeForClass(define *common.XMLDefine, options *common.Options) string {
+func generateDBCreateCodeForClass(define *common.Type, options *common.Options) string {
 	code := "\n"
+	table := define.Table()
+	// The table of a class added in or after the upgraded version is created
+	upgrade := options.IsUpgrade && !define.IsAddedSince(options.FromVersion)
 
-	if options.GenerateDropStatement == true {
-		code += fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", getDBTableName(define, options))
-	}
-
-	if options.IsUpgrade != true {
+	if !upgrade {
 		code += fmt.Sprintf("CREATE TABLE `%s` (\n", getDBTableName(define, options))
 	}
 
-	code += generateDBFieldCode(define, options)
+	code += generateDBFieldCode(define, table, options, upgrade)
 	// code += define.generateDBCreateCodeForField(options, define.Guids, "varchar(36)", true)
 	// code += define.generateDBCreateCodeForStrings(options, define.Strings)
 	// code += define.generateDBCreateCodeForField(options, define.Ints, "int(11)", false)
@@ -112,63 +369,149 @@ func generateDBCreateCodeForClass(define *common.XMLDefine, options *common.Opti
 	// code += define.generateDBCreateCodeForField(options, define.Enums, "int(11)", false)
 
 	// When not upgrading we need to close table creation statement
-	if !options.IsUpgrade {
-		// Insert primary key - this defaults to first GUID - could add XML attribute to class in order to define this
-		primaryKey := define.Fields[0]
-		code += fmt.Sprintf("  PRIMARY KEY(`%s`)\n", strings.ToLower(primaryKey.Name))
-		code += fmt.Sprintf(") ENGINE=InnoDB DEFAULT CHARSET=utf8;\n")
+	if !upgrade {
+		// Insert primary key - fields marked with 'primarykey', defaults to the first field
+		keyColumns := []string{}
+		for _, key := range table.Keys {
+			keyColumns = append(keyColumns, fmt.Sprintf("`%s`", key.GetDBColumnName(options)))
+		}
+		clauses := []string{fmt.Sprintf("PRIMARY KEY(%s)", strings.Join(keyColumns, ","))}
+		// Indexes of flattened parents are copied along with their columns
+		for _, source := range table.Sources {
+			for _, index := range source.Uniques {
+				clauses = append(clauses, fmt.Sprintf("UNIQUE KEY `%s` (%s)", index.IndexName(source.XMLDefine, true), generateDBIndexColumns(source, &index, options)))
+			}
+			for _, index := range source.Indexes {
+				clauses = append(clauses, fmt.Sprintf("KEY `%s` (%s)", index.IndexName(source.XMLDefine, false), generateDBIndexColumns(source, &index, options)))
+			}
+		}
+		clauses = append(clauses, generateDBForeignKeysForClass(define, options, false)...)
+		code += fmt.Sprintf("  %s\n", strings.Join(clauses, ",\n  "))
+		code += fmt.Sprintf(") ENGINE=InnoDB DEFAULT CHARSET=utf8%s;\n", dbComment("=", define.Documentation()))
+	} else {
+		for _, source := range table.Sources {
+			for _, index := range source.Uniques {
+				if index.FromVersion >= options.FromVersion {
+					code += fmt.Sprintf("CREATE UNIQUE INDEX `%s` ON `%s` (%s);\n", index.IndexName(source.XMLDefine, true), getDBTableName(define, options), generateDBIndexColumns(source, &index, options))
+				}
+			}
+			for _, index := range source.Indexes {
+				if index.FromVersion >= options.FromVersion {
+					code += fmt.Sprintf("CREATE INDEX `%s` ON `%s` (%s);\n", index.IndexName(source.XMLDefine, false), getDBTableName(define, options), generateDBIndexColumns(source, &index, options))
+				}
+			}
+		}
+		for _, constraint := range generateDBForeignKeysForClass(define, options, true) {
+			code += fmt.Sprintf("ALTER TABLE `%s` ADD %s;\n", getDBTableName(define, options), constraint)
+		}
+		code += generateDBDropColumnsCode(define, table, options)
 	}
 
 	return code
 }
 
-func generateDBFieldCode(define *common.XMLDefine, options *common.Options) string {
+// dbComment returns the COMMENT clause for a 'description' or <doc>, empty if there is none.
+// Tables use COMMENT='...' (separator "="), columns COMMENT '...' (separator " ").
+func dbComment(separator string, lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Replace(strings.Join(lines, "\n"), "\\", "\\\\", -1)
+	text = strings.Replace(text, "'", "''", -1)
+	text = strings.Replace(text, "\n", "\\n", -1)
+	return fmt.Sprintf(" COMMENT%s'%s'", separator, text)
+}
+
+// dbDefault returns the DEFAULT clause of a column with a default value, numbers and booleans are not quoted
+func dbDefault(field *common.Field) string {
+	if !field.HasDefault() {
+		return ""
+	}
+	value := field.DefaultValue()
+	switch {
+	case value == "true" || value == "false":
+		return fmt.Sprintf(" DEFAULT %s", strings.ToUpper(value))
+	case dbNumberRegexp.MatchString(value):
+		return fmt.Sprintf(" DEFAULT %s", value)
+	}
+	return fmt.Sprintf(" DEFAULT '%s'", strings.Replace(strings.Replace(value, "\\", "\\\\", -1), "'", "''", -1))
+}
+
+var dbNumberRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+func generateDBFieldCode(define *common.Type, table *common.Table, options *common.Options, upgrade bool) string {
 	code := ""
 	firstField := true
-	for _, field := range define.Fields {
-		if field.SkipPersistance == true {
+	for _, field := range table.Fields {
+		if !field.IsPersisted() {
 			continue
 		}
-		if options.IsUpgrade {
+		if upgrade {
 			if field.FromVersion >= options.FromVersion {
-				defaultValue := field.Default
-				if len(defaultValue) == 0 {
-					// Ok with empty strings
+				defaultValue := dbDefault(field)
+				if field.IsNullable() {
+					// No default value required, existing rows get NULL
+					if defaultValue == "" {
+						defaultValue = " DEFAULT NULL"
+					}
+					code += fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s NULL%s%s;\n",
+						getDBTableName(define, options),
+						field.GetDBColumnName(options),
+						field.MappedType(common.LangDB),
+						defaultValue,
+						dbComment(" ", field.Documentation()))
+					continue
+				}
+				if !field.HasDefault() {
+					// Existing rows get the implicit default of the type (0, '')
 					log.Printf("!WARNING!: Upgrade require field default values, check definition of '%s::%s'\n", define.Name, field.Name)
 				}
-				code += fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s NOT NULL DEFAULT '%s';\n",
+				code += fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s NOT NULL%s%s;\n",
 					getDBTableName(define, options),
 					field.GetDBColumnName(options),
 					//field.getDBType(options),
-					field.TypeMapping(options.CurrentDoc.DBTypeMappings),
-					defaultValue)
+					field.MappedType(common.LangDB),
+					defaultValue,
+					dbComment(" ", field.Documentation()))
 			}
 		} else {
+			nullStatement := "NOT NULL"
+			if field.IsNullable() {
+				nullStatement = "NULL"
+			}
+			additional := dbDefault(field) + field.AdditionalDBCreateStatement(options)
+			if table.Joined && table.IsKey(field) {
+				// The key is generated by the parent table
+				additional = ""
+			}
+			additional += dbComment(" ", field.Documentation())
 			if firstField {
-				code += fmt.Sprintf("  `%s` %s NOT NULL %s,\n",
+				code += fmt.Sprintf("  `%s` %s %s %s,\n",
 					field.GetDBColumnName(options),
-					field.TypeMapping(options.CurrentDoc.DBTypeMappings),
-					field.AdditionalDBCreateStatement(options))
+					field.MappedType(common.LangDB),
+					nullStatement,
+					additional)
 				firstField = false
 			} else {
-				code += fmt.Sprintf("  `%s` %s NOT NULL %s,\n",
+				code += fmt.Sprintf("  `%s` %s %s %s,\n",
 					field.GetDBColumnName(options),
-					field.TypeMapping(options.CurrentDoc.DBTypeMappings),
-					field.AdditionalDBCreateStatement(options))
+					field.MappedType(common.LangDB),
+					nullStatement,
+					additional)
 			}
 		}
 	}
 	return code
 }
 
-func generateDBCreateCodeForField(define *common.XMLDefine, options *common.Options, list []common.XMLDataTypeField, typeString string, isPrimary bool) string {
+func generateDBCreateCodeForField(define *common.Type, options *common.Options, list []common.XMLDataTypeField, typeString string, isPrimary bool) string {
 	code := ""
 
 	firstField := isPrimary
 	for _, field := range list {
 		if options.IsUpgrade {
 			if field.FromVersion >= options.FromVersion {
-				defaultValue := field.Default
+				defaultValue := field.DefaultValue()
 				if len(defaultValue) == 0 {
 					log.Fatalf("!Error: Upgrade require field default values!\nCheck definition of '%s::%s`\n", define.Name, field.Name)
 				}
@@ -190,7 +533,7 @@ func generateDBCreateCodeForField(define *common.XMLDefine, options *common.Opti
 	return code
 }
 
-func generateDBCreateCodeForStrings(define *common.XMLDefine, options *common.Options, list []common.XMLDataTypeField) string {
+func generateDBCreateCodeForStrings(define *common.Type, options *common.Options, list []common.XMLDataTypeField) string {
 	code := ""
 
 	for _, field := range list {
@@ -201,7 +544,7 @@ func generateDBCreateCodeForStrings(define *common.XMLDefine, options *common.Op
 		}
 		if options.IsUpgrade {
 			if field.FromVersion >= options.FromVersion {
-				defaultValue := field.Default
+				defaultValue := field.DefaultValue()
 				if len(defaultValue) == 0 {
 					log.Fatalf("Upgrade require field default values!\nCheck definition of '%s::%s`\n", define.Name, field.Name)
 				}
@@ -224,7 +567,7 @@ func generateDBCreateCodeForStrings(define *common.XMLDefine, options *common.Op
 	return code
 }
 
-func generateDBCreateCodeForFieldWithoutNULL(define *common.XMLDefine, list []common.XMLDataTypeField, typeString string) string {
+func generateDBCreateCodeForFieldWithoutNULL(define *common.Type, list []common.XMLDataTypeField, typeString string) string {
 	code := ""
 	for _, field := range list {
 		name := strings.ToLower(field.Name)
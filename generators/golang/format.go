@@ -0,0 +1,117 @@
+package golang
+
+//
+// Formats the generated GO code, imports which are not used by the code are removed and the result is gofmt'ed
+//
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// formatGenerated removes the unused imports from the generated file and formats it, code which doesn't parse is an
+// error naming the file and the offending line
+func formatGenerated(filename string, code string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, parser.ParseComments)
+	if err != nil {
+		return "", parseError(filename, code, err)
+	}
+
+	// The import declarations are replaced by one declaration with the used imports (and their comments)
+	used := usedPackages(file)
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	imports := ""
+	start, end := -1, -1
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		if start < 0 {
+			start = offset(decl.Pos())
+		}
+		end = offset(decl.End())
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if name := importName(spec); name != "_" && name != "." && !used[name] {
+				continue
+			}
+			if spec.Doc != nil {
+				for _, comment := range spec.Doc.List {
+					imports += comment.Text + "\n"
+				}
+			}
+			imports += code[offset(spec.Pos()):offset(spec.End())] + "\n"
+		}
+	}
+	if start >= 0 {
+		if imports != "" {
+			imports = "import (\n" + imports + ")"
+		}
+		code = code[:start] + imports + code[end:]
+	}
+
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", parseError(filename, code, err)
+	}
+	return string(formatted), nil
+}
+
+// parseError returns the first syntax error of the generated code with the line it's on
+func parseError(filename string, code string, err error) error {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		lines := strings.Split(code, "\n")
+		line := list[0].Pos.Line
+		if line > 0 && line <= len(lines) {
+			return fmt.Errorf("generated code for '%s' doesn't parse: %s\n%6d | %s", filename, list[0], line, lines[line-1])
+		}
+	}
+	return fmt.Errorf("generated code for '%s' doesn't parse: %s", filename, err)
+}
+
+// usedPackages returns the names used as package qualifier, identifiers which aren't declared in the file
+func usedPackages(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+var importVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName returns the name an import is used with, the alias or the (guessed) package name of the path
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	importPath, _ := strconv.Unquote(spec.Path.Value)
+	name := path.Base(importPath)
+	// 'gopkg.in/yaml.v3', 'github.com/mattn/go-sqlite3' and '.../v2' paths
+	if importVersion.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	if idx := strings.Index(name, ".v"); idx > 0 {
+		name = name[:idx]
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "go-"), "go.")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return strings.Replace(name, "-", "", -1)
+}
@@ -3,14 +3,18 @@ package golang
 import "modelgenerator/common"
 
 type CrudGenerator struct {
-	Imports []common.XMLImport
+	Imports   []common.XMLImport
+	templates *common.Templates
+
+	// fetch function per generated class, set before the code is generated
+	fetchFunctions map[string]string
 }
 
 type DBGenerator struct{}
 
 type CodeGenerator struct {
-	Methods []common.AccessMethod
-	Imports []common.XMLImport
+	Imports   []common.XMLImport
+	templates *common.Templates
 }
 
 type GoLangGenerators struct{}
@@ -32,6 +36,31 @@ func CreateGoLanguage() common.Language {
 	return (common.Language)(&golang)
 }
 
+func init() {
+	common.RegisterLanguage(common.LanguageInfo{
+		Name:        "go",
+		Aliases:     []string{"golang"},
+		Description: "GO structs, MySQL persistence (CRUD) and DB create/upgrade scripts",
+		Create:      CreateGoLanguage,
+		Options: []common.LanguageOption{
+			{Name: "package", Arg: "name", Help: "package name of the generated files, default is the last part of the namespace"},
+			{Name: "module", Arg: "path", Help: "module path of the packages of referenced includes (same as -G)",
+				Apply: func(options *common.Options, value string) error {
+					options.GoModulePath = value
+					return nil
+				}},
+		},
+	})
+}
+
+// packageName returns the package of the generated files, the 'package' option or the namespace of the model
+func packageName(model *common.Model, options *common.Options) string {
+	if name := options.LanguageOption("package"); name != "" {
+		return name
+	}
+	return model.Doc.PackageName()
+}
+
 func createGoLangGenerator() common.Generator {
 	codeGen := CodeGenerator{}
 	return (common.Generator)(&codeGen)
@@ -4,47 +4,118 @@ import (
 	"fmt"
 	"log"
 	common "modelgenerator/common"
+	"path/filepath"
 	"strings"
 )
 
-func (generator *CodeGenerator) GenerateCode(doc common.XMLDoc, options *common.Options) string {
+func (generator *CodeGenerator) GenerateCode(model *common.Model, options *common.Options) (string, error) {
+	if err := generator.prepare(model, options); err != nil {
+		return "", err
+	}
+	code, err := generator.generateHeader(model, options)
+	if err != nil {
+		return "", err
+	}
+	if options.GenerateValidation {
+		validationCode, err := generator.generateValidationErrorType(options)
+		if err != nil {
+			return "", err
+		}
+		code += validationCode
+	}
+	// generate code for all defines
+	for _, define := range model.Types {
+		defineCode, err := generator.generateCode(options, define)
+		if err != nil {
+			return "", err
+		}
+		code += defineCode
+	}
+	return formatGenerated(options.OutputName, code)
+}
 
-	code := ""
+// GenerateFiles generates a file per define named after the define, the validation error type is in 'model_base.go'
+func (generator *CodeGenerator) GenerateFiles(model *common.Model, options *common.Options) ([]common.GeneratedFile, error) {
+	if err := generator.prepare(model, options); err != nil {
+		return nil, err
+	}
+	files := []common.GeneratedFile{}
+	if options.GenerateValidation {
+		code, err := generator.generateValidationErrorType(options)
+		if err != nil {
+			return nil, err
+		}
+		file, err := generator.generateFile(model, options, "model_base.go", code)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	for _, define := range model.Types {
+		code, err := generator.generateCode(options, define)
+		if err != nil {
+			return nil, err
+		}
+		file, err := generator.generateFile(model, options, strings.ToLower(define.Name)+".go", code)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// IsGeneratedFile returns true for the GO files of the model, the persistence files can be in the same directory
+func (generator *CodeGenerator) IsGeneratedFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !isPersistenceFile(name)
+}
 
-	generator.Imports = doc.Imports
+// generateFile adds the header to the code of a split file, the imports not used by the file are dropped by formatting
+func (generator *CodeGenerator) generateFile(model *common.Model, options *common.Options, name string, code string) (common.GeneratedFile, error) {
+	header, err := generator.generateHeader(model, options)
+	if err != nil {
+		return common.GeneratedFile{}, err
+	}
+	code, err = formatGenerated(filepath.Join(options.OutputName, name), header+code)
+	return common.GeneratedFile{Name: name, Code: code}, err
+}
+
+// prepare loads the templates and collects the imports the generated code may need
+func (generator *CodeGenerator) prepare(model *common.Model, options *common.Options) error {
+	templates, err := loadTemplates(options)
+	if err != nil {
+		return err
+	}
+	generator.templates = templates
+	generator.Imports = append([]common.XMLImport{}, model.Doc.Imports...)
+	// Types of referenced documents are declared in the package generated for the referenced document
+	for _, refDoc := range model.UsedReferences() {
+		if options.GoModulePath == "" {
+			// without the module the import path is the bare namespace, which doesn't resolve in a Go module
+			return fmt.Errorf("the types of the referenced namespace '%s' need the Go module path of its package (-G <module>)", refDoc.Namespace)
+		}
+		generator.addImport(refDoc.GoImportPath(options.GoModulePath))
+	}
 	// Creating To/From - converters for model, add necessary imports
 	if options.Converters {
 		generator.addImport("bytes")         //append(doc.Imports, "bytes")
 		generator.addImport("encoding/json") //append(doc.Imports, "encoding/json")
 		generator.addImport("encoding/xml")  //append(doc.Imports, "encoding/xml")
 		generator.addImport("fmt")
+		generator.addImport("strconv")
 	}
-
-	if options.SplitInFiles == true {
-		log.Printf("Split In Files not supported!\n")
-		return code
-	} else {
-		code += generator.generateHeader(doc, options.Filename)
-		// generate code for all defines
-		for _, define := range doc.Defines {
-			//log.Printf("Generate for define: %s\n", define.Name)
-			generator.Methods = nil
-			code += generator.generateCode(options, &define)
-			// if string(outputDir[len(outputDir)-1:]) != "/" {
-			// 	outputDir += "/"
-			// }
-			// fileName := outputDir + define.Name + ".go"
-
-			// if options.Verbose > 0 {
-			// 	fmt.Printf("Writing code for %s to %s\n", define.Name, fileName)
-			// }
-			// ioutil.WriteFile(fileName, []byte(code), 0644)
-		}
+	if options.GenerateValidation {
+		generator.addValidationImports(model)
 	}
-	return code
+	return nil
 }
 
 func (generator *CodeGenerator) addImport(pkgName string) {
+	for _, Import := range generator.Imports {
+		if Import.Package == pkgName {
+			return
+		}
+	}
 	generator.Imports = append(generator.Imports, common.XMLImport{
 		DisablePersistence: false,
 		Package:            pkgName,
@@ -56,389 +127,88 @@ func (generator *CodeGenerator) addImport(pkgName string) {
 	// return doc.Imports
 }
 
-func (generator *CodeGenerator) generateHeader(doc common.XMLDoc, modelSourceName string) string {
-
+// docComment returns the godoc comment for a 'description' or <doc>, empty if there is none
+func docComment(lines []string, indent string) string {
 	code := ""
-	code += fmt.Sprintf("package %s\n", doc.Namespace)
-	code += fmt.Sprintf("\n")
-	if len(doc.Imports) > 0 {
-		code += fmt.Sprintf("import (\n")
-		for _, Import := range doc.Imports {
-			//log.Printf("Import: %s", Import.Package)
-			importstatements := strings.Split(Import.Package, " ")
-			if len(importstatements) == 1 {
-				code += fmt.Sprintf("  \"%s\"\n", Import.Package)
-			} else {
-				code += fmt.Sprintf("  %s \"%s\"\n", importstatements[0], importstatements[1])
-			}
-
-		}
-		code += fmt.Sprintf(")\n")
+	for _, line := range lines {
+		code += strings.TrimRight(fmt.Sprintf("%s// %s", indent, line), " ") + "\n"
 	}
+	return code
+}
 
-	code += fmt.Sprintf("//\n")
-	code += fmt.Sprintf("// this code is generated by the modelgenerator\n")
-	code += fmt.Sprintf("// data model source = %s\n", modelSourceName)
-	code += fmt.Sprintf("//\n")
-	code += fmt.Sprintf("\n")
+func (generator *CodeGenerator) generateHeader(model *common.Model, options *common.Options) (string, error) {
+	return generator.templates.Execute("header", templateHeader{
+		Package: packageName(model, options),
+		Source:  options.Filename,
+		Imports: templateImports(generator.Imports),
+		Doc:     model.Doc,
+	})
+}
 
-	return code
+// generateValidationErrorType generates the error type returned by all Validate methods
+func (generator *CodeGenerator) generateValidationErrorType(options *common.Options) (string, error) {
+	return generator.templates.Execute("validationErrorType", templateType{Options: options})
 }
-func (generator *CodeGenerator) generateCode(options *common.Options, define *common.XMLDefine) string {
-	code := ""
 
+func (generator *CodeGenerator) generateCode(options *common.Options, define *common.Type) (string, error) {
 	log.Printf("Generating code for type='%s', named='%s'\n", define.Type, define.Name)
+	data := templateType{Type: define, Options: options}
 	switch define.Type {
 	case "class":
-		code += generator.generateClassCode(options, define)
+		names := []string{"class"}
 		if options.Converters {
-			code += generator.generateClassConverters(define)
+			names = append(names, "converters")
+		}
+		if options.GenerateValidation {
+			names = append(names, "validate")
 		}
-		break
+		return executeTemplates(generator.templates, names, data)
 	case "enum":
-		code += generator.generateEnumCode(options, define)
-		break
+		return generator.templates.Execute("enum", data)
 	default:
 		fmt.Printf("[GolangModelGenerator::generateCode] Error, can't generate code for type '%s'\n", define.Type)
-		break
 	}
-
-	return code
+	return "", nil
 }
 
-// generateEnumCode creates Go Code for an ENUM const declaration
-func (generator *CodeGenerator) generateEnumCode(options *common.Options, define *common.XMLDefine) string {
-
-	code := ""
-	code += fmt.Sprintf("type %s int64\n", define.Name)
-	code += fmt.Sprintf("const (\n")
-	code += fmt.Sprintf("  _ = iota\n")
-	for _, Int := range define.Ints {
-		code += fmt.Sprintf("  %s %s = %d\n", Int.Name, define.Name, Int.Value)
-	}
-	code += fmt.Sprintf(")\n")
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("var map%sToName = map[%s]string {\n", define.Name, define.Name)
-	for _, Int := range define.Ints {
-		code += fmt.Sprintf("  %d:\"%s\",\n", Int.Value, Int.Name)
-	}
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("var map%sToValue = map[string]%s {\n", define.Name, define.Name)
-	for _, Int := range define.Ints {
-		code += fmt.Sprintf("  \"%s\":%d,\n", Int.Name, Int.Value)
-	}
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	if options.Converters {
-		code += fmt.Sprintf("func (this *%s) String() string {\n", define.Name)
-		code += fmt.Sprintf("  return map%sToName[*this]\n", define.Name)
-		code += fmt.Sprintf("}\n")
-		code += fmt.Sprintf("\n")
-
-		code += fmt.Sprintf("func (this %s) MarshalJSON() ([]byte, error) {\n", define.Name)
-		code += fmt.Sprintf("  return json.Marshal(this.String())\n")
-		code += fmt.Sprintf("}\n")
-		code += fmt.Sprintf("\n")
-
-		code += fmt.Sprintf("func (this *%s) UnmarshalJSON(data []byte) error {\n", define.Name)
-		code += fmt.Sprintf("  var s string\n")
-		code += fmt.Sprintf("  if err := json.Unmarshal(data, &s); err != nil {\n")
-		code += fmt.Sprintf("    numeric, err := strconv.Atoi(string(data))\n")
-		code += fmt.Sprintf("    if err != nil {\n")
-		code += fmt.Sprintf("      return fmt.Errorf(\"%s should be a string\")\n", define.Name)
-		code += fmt.Sprintf("    }\n")
-		code += fmt.Sprintf("    _, ok := map%sToName[%s(numeric)]\n", define.Name, define.Name)
-		code += fmt.Sprintf("    if !ok {\n")
-		code += fmt.Sprintf("      return fmt.Errorf(\"invalid %s in object\")\n", define.Name)
-		code += fmt.Sprintf("    }\n")
-		code += fmt.Sprintf("    *this = %s(numeric)\n", define.Name)
-		code += fmt.Sprintf("  } else {\n")
-		code += fmt.Sprintf("    v, ok := map%sToValue[s]\n", define.Name)
-		code += fmt.Sprintf("    if !ok {\n")
-		code += fmt.Sprintf("      return fmt.Errorf(\"invalid %s in object\")\n", define.Name)
-		code += fmt.Sprintf("    }\n")
-		code += fmt.Sprintf("    *this = v\n")
-		code += fmt.Sprintf("  }\n")
-		code += fmt.Sprintf("  return nil\n")
-		code += fmt.Sprintf("}")
-		code += fmt.Sprintf("\n")
-	}
-	return code
-}
-
-func (generator *CodeGenerator) generateClassCode(options *common.Options, define *common.XMLDefine) string {
-
-	code := ""
-
-	code += fmt.Sprintf("//\n")
-	code += fmt.Sprintf("// %s is generated\n", define.Name)
-	code += fmt.Sprintf("//\n")
-
-	code += fmt.Sprintf("type %s struct {\n", define.Name)
-
-	if define.Inherits != "" {
-		code += fmt.Sprintf("  %s\n\n", define.Inherits)
-	}
-
-	code += generator.generateFields(options, define)
-
-	// code += define.generateFieldCode(define.Guids, "uuid.UUID")
-	// code += define.generateFieldCode(define.Strings, "string")
-	// code += define.generateFieldCode(define.Ints, "int")
-	// code += define.generateFieldCode(define.Bools, "bool")
-	// // TODO: Fix this!!
-	// code += define.generateFieldCode(define.Times, "time.Time")
-	// code += define.generateFieldListCode(define.Lists)
-	// code += define.generateFieldEnumCode(define.Enums)
-	// code += define.generateFieldObjectCode(define.Objects)
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	//	code += generator.generateConstructor(options, define)
-
-	if options.GettersAndSetters {
-		//		log.Printf("Generate Getters and Setter for: %s\n", define.Name)
-		code += generator.generateGettersAndSettersForDefine(options, define)
-	}
-
-	return code
-}
-
-func (generator *CodeGenerator) generateConstructor(options *common.Options, define *common.XMLDefine) string {
-	code := ""
-
-	code += fmt.Sprintf("func New%s() %s {\n", define.Name, define.Name)
-	code += fmt.Sprintf("  inst := %s{}\n", define.Name)
-	for _, f := range define.Fields {
-		if len(f.Default) > 0 {
-			code += fmt.Sprintf("  inst.%s = %s\n", f.Name, f.Default)
+// fieldDocumentation returns the doc comment lines of a field, including the deprecation note
+func fieldDocumentation(field *common.Field) []string {
+	lines := field.Documentation()
+	if note := field.DeprecationNote(); note != "" {
+		// godoc wants the deprecation in a paragraph of its own
+		if len(lines) > 0 {
+			lines = append(lines, "")
 		}
+		lines = append(lines, "Deprecated: "+note)
 	}
-	code += fmt.Sprintf("  return inst\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	return code
+	return lines
 }
 
-func (generator *CodeGenerator) generateGettersAndSettersForDefine(options *common.Options, define *common.XMLDefine) string {
-	code := ""
-	for _, method := range generator.Methods {
-		ptrAttrib := "*"
-		if method.IsPointer != true {
-			ptrAttrib = ""
-		}
-		if method.Define.Name != define.Name {
-			continue
-		}
-
-		//		log.Printf("Generating getter/setter for: %s::%s (%s)\n", method.Define.Name, method.Name, define.Name)
-
-		if method.Getter == true {
-			if method.IsList != true {
-				code += fmt.Sprintf("func (this *%s) Get%s() %s%s {\n", define.Name, method.Name, ptrAttrib, method.Type)
-				code += fmt.Sprintf("  return this.%s\n", method.Name)
-				code += fmt.Sprintf("}\n")
-				code += fmt.Sprintf("\n")
-			} else {
-				code += fmt.Sprintf("func (this *%s) Get%sAsRef() []%s%s {\n", define.Name, method.Name, ptrAttrib, method.Type)
-				code += fmt.Sprintf("  return this.%s[:len(this.%s)]\n", method.Name, method.Name)
-				code += fmt.Sprintf("}\n")
-				code += fmt.Sprintf("\n")
-
-				code += fmt.Sprintf("func (this *%s) Get%sAsCopy() []%s%s {\n", define.Name, method.Name, ptrAttrib, method.Type)
-				code += fmt.Sprintf("  newSlice := make([]%s, len(this.%s))\n", method.Type, method.Name)
-				code += fmt.Sprintf("  copy(newSlice, this.%s)\n", method.Name)
-				code += fmt.Sprintf("  return newSlice\n")
-				code += fmt.Sprintf("}\n")
-				code += fmt.Sprintf("\n")
-			}
-		}
-
-		if method.Setter == true {
-			if method.IsList != true {
-				code += fmt.Sprintf("func (this *%s) Set%s(value %s%s) {\n", define.Name, method.Name, ptrAttrib, method.Type)
-				code += fmt.Sprintf("  this.%s = value\n", method.Name)
-				code += fmt.Sprintf("}\n")
-				code += fmt.Sprintf("\n")
-			} else {
-				code += fmt.Sprintf("func (this *%s) Set%s(value []%s%s) {\n", define.Name, method.Name, ptrAttrib, method.Type)
-				code += fmt.Sprintf("  this.%s = make([]%s, len(value))\n", method.Name, method.Type)
-				code += fmt.Sprintf("  copy(this.%s, value)\n", method.Name)
-				code += fmt.Sprintf("}\n")
-				code += fmt.Sprintf("\n")
-			}
-		}
-	}
-	return code
-}
-
-func (generator *CodeGenerator) generateFields(options *common.Options, define *common.XMLDefine) string {
-	code := ""
-
-	//	log.Printf("Generate fields for: %s\n", define.Name)
-	for _, field := range define.Fields {
-		//		log.Printf("  Field: %s\n", field.Name)
-		code += generator.goFieldCode(options, &field)
-		generator.methodFromField(define, field, field.TypeMapping(options.CurrentDoc.GOTypeMappings), field.IsList)
-	}
-
-	return code
-}
-
-func (generator *CodeGenerator) goFieldCode(options *common.Options, field *common.XMLDataTypeField) string {
-	code := ""
-
+// goType returns the declared GO type of a field
+func goType(field *common.Field) string {
 	typePrefix := ""
 	if field.IsList {
 		typePrefix = typePrefix + "[]"
 	}
-	if field.IsPointer {
+	// Nullable fields are pointers, nil is NULL in the DB and omitted in JSON/XML
+	if field.IsPointer || field.IsNullable() {
 		typePrefix = typePrefix + "*"
 	}
-	code += fmt.Sprintf("  %s %s%s", field.Name, typePrefix, field.TypeMapping(options.CurrentDoc.GOTypeMappings))
-
-	if field.XMLAttrib != "" {
-		code += fmt.Sprintf("`xml:\"%s\"`", field.XMLAttrib)
-	}
-	code += fmt.Sprintf("\n")
-
-	return code
+	return typePrefix + field.MappedType(common.LangGo)
 }
 
-func (generator *CodeGenerator) methodFromField(define *common.XMLDefine, field common.XMLDataTypeField, typeString string, isList bool) {
-
-	method := common.AccessMethod{
-		Define:    define,
-		Getter:    true,
-		Setter:    true,
-		IsList:    isList,
-		Name:      field.Name,
-		Type:      typeString,
-		IsPointer: field.IsPointer,
-		NoPersist: field.SkipPersistance,
-		AutoID:    field.DBAutoID,
+// goTags returns the struct tags of a field, without the back quotes
+func goTags(field *common.Field) string {
+	tags := []string{}
+	if field.IsNullable() {
+		tags = append(tags, fmt.Sprintf("json:\"%s,omitempty\"", field.Name))
 	}
-	//	log.Printf("  methodFromField: %s, %s, %s\n", define.Name, typeString, field.Name)
-	generator.Methods = append(generator.Methods, method)
-}
-
-//
-// Class converters
-//
-func (generator *CodeGenerator) generateClassConverters(define *common.XMLDefine) string {
-	code := ""
-
-	code += generator.generateToJSONCode(define)
-	code += fmt.Sprintf("\n")
-	code += generator.generateToXMLCode(define)
-	code += fmt.Sprintf("\n")
-
-	code += generator.generateFromJSONCode(define)
-	code += fmt.Sprintf("\n")
-	code += generator.generateFromXMLCode(define)
-	code += fmt.Sprintf("\n")
-	return code
-}
-
-func (generator *CodeGenerator) generateToJSONCode(define *common.XMLDefine) string {
-	code := ""
-	code += fmt.Sprintf("// ToJSON creates a JSON representation of the data for the type\n")
-	code += fmt.Sprintf("func (this *%s) ToJSON() string {\n", define.Name)
-
-	code += fmt.Sprintf("  b, err := json.MarshalIndent(this, \"\", \"    \")\n")
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("    return \"\"\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  return bytes.NewBuffer(b).String()\n")
-	code += fmt.Sprintf("}\n")
-
-	// code += fmt.Sprintf("  b := new(bytes.Buffer)\n")
-	// code += fmt.Sprintf("  encoder := json.NewEncoder(b)\n")
-	// code += fmt.Sprintf("  encoder.SetIndent(\"\", \"    \")\n")
-	// code += fmt.Sprintf("  err := encoder.Encode(this)\n")
-	// code += fmt.Sprintf("  if err != nil {\n")
-	// code += fmt.Sprintf("    return \"\"\n")
-	// code += fmt.Sprintf("  }\n")
-	// code += fmt.Sprintf("  return b.String()\n")
-	return code
-}
-
-func (generator *CodeGenerator) generateToXMLCode(define *common.XMLDefine) string {
-	code := ""
-	code += fmt.Sprintf("// ToXML creates an XML representation of the data for the type\n")
-	code += fmt.Sprintf("func (this *%s) ToXML() string {\n", define.Name)
-
-	code += fmt.Sprintf("  b, err := xml.MarshalIndent(this, \"\", \"    \")\n")
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("    return \"\"\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  return bytes.NewBuffer(b).String()\n")
-	code += fmt.Sprintf("}\n")
-
-	// code += fmt.Sprintf("  b := new(bytes.Buffer)\n")
-	// code += fmt.Sprintf("  encoder := xml.NewEncoder(b)\n")
-	// code += fmt.Sprintf("  encoder.Indent(\"\", \"    \")\n")
-	// code += fmt.Sprintf("  err := encoder.Encode(this)\n")
-	// code += fmt.Sprintf("  if err != nil {\n")
-	// code += fmt.Sprintf("    return \"\"\n")
-	// code += fmt.Sprintf("  }\n")
-	// code += fmt.Sprintf("  return b.String()\n")
-	return code
-}
-
-func (generator *CodeGenerator) generateFromXMLCode(define *common.XMLDefine) string {
-	code := ""
-
-	code += fmt.Sprintf("// %sFromXML converts an XML representation to the type\n", define.Name)
-	code += fmt.Sprintf("func %sFromXML(xmldata string) (*%s, error) {\n", define.Name, define.Name)
-	code += fmt.Sprintf("  var value %s\n", define.Name)
-	code += fmt.Sprintf("  err := xml.Unmarshal([]byte(xmldata), &value)\n")
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("	  return nil, err\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  return &value, nil\n")
-	code += fmt.Sprintf("}\n")
-
-	return code
-
-	// -- Original code (this is what it should be)
-	// func UserFromXML(xmldata string) (*User, error) {
-	// 	var user User
-	// 	err := xml.Unmarshal([]byte(xmldata), &user)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	return &user, nil
-	// }
-
-}
-
-func (generator *CodeGenerator) generateFromJSONCode(define *common.XMLDefine) string {
-	code := ""
-
-	code += fmt.Sprintf("// %sFromJSON converts a JSON representation to the data type\n", define.Name)
-	code += fmt.Sprintf("func %sFromJSON(jsondata string) (*%s, error) {\n", define.Name, define.Name)
-	code += fmt.Sprintf("  var value %s\n", define.Name)
-	code += fmt.Sprintf("  err := json.Unmarshal([]byte(jsondata), &value)\n")
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("	  return nil, err\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  return &value, nil\n")
-	code += fmt.Sprintf("}\n")
-	return code
-
-	// -- original code
-	// func UserFromJSON(jsondata string) (*User, error) {
-	// 	var user User
-	// 	err := json.Unmarshal([]byte(jsondata), &user)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	return &user, nil
-	// }
+	if field.XMLAttrib != "" {
+		if field.IsNullable() && !strings.Contains(field.XMLAttrib, "omitempty") {
+			tags = append(tags, fmt.Sprintf("xml:\"%s,omitempty\"", field.XMLAttrib))
+		} else {
+			tags = append(tags, fmt.Sprintf("xml:\"%s\"", field.XMLAttrib))
+		}
+	}
+	return strings.Join(tags, " ")
 }
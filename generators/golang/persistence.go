@@ -7,496 +7,408 @@ package golang
 
 import (
 	"fmt"
-	"io/ioutil"
+	"go/token"
 	"log"
 	"modelgenerator/common"
+	"path"
+	"path/filepath"
 	"strings"
 )
 
-// This is ugly but I don't want to rewrite fetchQueryFromString to be type-qualified in the function name.
-// In case we are generating multiple classes for one domain it is required that the fetch function is different as GO don't support polymorphic functions
-// This is set to 'true' after the first round of classes has been done
-var createRetrieveFuncPostfix = false
-
-// func generatePersistenceCode(doc XMLDoc, className string, source string, splitInFiles bool, converters bool, verbose int, outputDir string) string {
-
-func (generator *CrudGenerator) GenerateCode(doc common.XMLDoc, options *common.Options) string {
-	code := ""
-
-	/*
-		if options.Converters {
-			generator.addImport(&doc, "bytes")         //append(doc.Imports, "bytes")
-			generator.addImport(&doc, "encoding/json") //append(doc.Imports, "encoding/json")
-			generator.addImport(&doc, "encoding/xml")  //append(doc.Imports, "encoding/xml")
-
-			// doc.Imports = append(doc.Imports, "bytes")
-			// doc.Imports = append(doc.Imports, "encoding/json")
-			// doc.Imports = append(doc.Imports, "encoding/xml")
+func (generator *CrudGenerator) GenerateCode(model *common.Model, options *common.Options) (string, error) {
+	defines, err := generator.prepare(model, options)
+	if err != nil {
+		return "", err
+	}
+	code, err := generator.generatePersistenceHeader(model, options)
+	if err != nil {
+		return "", err
+	}
+	// generate code for all defines
+	for _, define := range defines {
+		defineCode, err := generator.generatePersistenceCodeForDefine(define, options)
+		if err != nil {
+			return "", err
 		}
-	*/
-	if options.SplitInFiles != true {
-		code += generator.generatePersistenceHeader(doc, options)
-		// generate code for all defines
-		for i := 0; i < len(doc.Defines); i++ {
-			if doc.Defines[i].SkipPersistance == true {
-				fmt.Printf("Skipping: %s\n", doc.Defines[i].Name)
-				continue
-			}
-			if strings.Compare(options.PersistenceClass, "-") != 0 {
-				for j := 0; j < len(options.AllPersistenceClasses); j++ {
-					code += generatePersistenceCodeForDefine(&doc.Defines[i], options, options.AllPersistenceClasses[j], options.Converters)
-				}
-			} else {
-				code += generatePersistenceCodeForDefine(&doc.Defines[i], options, options.PersistenceClass, options.Converters)
-			}
+		code += defineCode
+	}
+	// Navigation helpers at the end, after the code of all classes
+	for _, define := range defines {
+		relationCode, err := generator.generateRelationCode(define, options)
+		if err != nil {
+			return "", err
 		}
-	} else {
-		log.Panicln("SPLIT IN FILES NOT SUPPORTED!!!!")
-
-		// generate code for all defines
-		for _, define := range doc.Defines {
-			code := ""
-			code += generator.generatePersistenceHeader(doc, options)
-			code += generatePersistenceCodeForDefine(&define, options, options.PersistenceClass, options.Converters)
-			outputDir := options.OutputDBName
-			if string(outputDir[len(outputDir)-1:]) != "/" {
-				outputDir += "/"
-			}
-			fileName := outputDir + define.Name + ".go"
+		code += relationCode
+	}
+	return formatGenerated(options.OutputDBName, code)
+}
 
-			if options.Verbose > 0 {
-				fmt.Printf("Writing code for %s to %s\n", define.Name, fileName)
-			}
-			ioutil.WriteFile(fileName, []byte(code), 0644)
+// GenerateFiles generates 'persistence_base.go' with the DB connection and a file per class with its CRUD functions
+// and navigation helpers, like 'orderitem_persistence.go' for 'OrderItem'
+func (generator *CrudGenerator) GenerateFiles(model *common.Model, options *common.Options) ([]common.GeneratedFile, error) {
+	defines, err := generator.prepare(model, options)
+	if err != nil {
+		return nil, err
+	}
+	header, err := generator.generatePersistenceHeader(model, options)
+	if err != nil {
+		return nil, err
+	}
+	file, err := generateFile(options.OutputDBName, "persistence_base.go", header)
+	if err != nil {
+		return nil, err
+	}
+	files := []common.GeneratedFile{file}
+	for _, define := range defines {
+		code, err := generator.generatePersistenceFile(model, define, options)
+		if err != nil {
+			return nil, err
+		}
+		file, err := generateFile(options.OutputDBName, strings.ToLower(define.Name)+"_persistence.go", code)
+		if err != nil {
+			return nil, err
 		}
+		files = append(files, file)
 	}
-	return code
+	return files, nil
 }
 
-// func (generator *CrudGenerator) addImport(pkgName string) {
-// 	generator.Imports = append(generator.Imports, common.XMLImport{
-// 		DisablePersistence: false,
-// 		Package:            pkgName,
-// 	})
-// }
+// generatePersistenceFile generates the code of a class file of a split output, the CRUD functions and navigation helpers
+func (generator *CrudGenerator) generatePersistenceFile(model *common.Model, define *common.Type, options *common.Options) (string, error) {
+	code, err := generator.generatePersistenceFileHeader(model, options)
+	if err != nil {
+		return "", err
+	}
+	defineCode, err := generator.generatePersistenceCodeForDefine(define, options)
+	if err != nil {
+		return "", err
+	}
+	relationCode, err := generator.generateRelationCode(define, options)
+	if err != nil {
+		return "", err
+	}
+	return code + defineCode + relationCode, nil
+}
 
-func (generator *CrudGenerator) generatePersistenceHeader(doc common.XMLDoc, options *common.Options) string {
+// IsGeneratedFile returns true for 'persistence_base.go' and the class files of the persistence
+func (generator *CrudGenerator) IsGeneratedFile(name string) bool {
+	return isPersistenceFile(name)
+}
 
-	code := ""
-	code += fmt.Sprintf("package %s\n", doc.Namespace)
-	code += fmt.Sprintf("\n")
-	if len(doc.Imports) > 0 {
-		code += fmt.Sprintf("import (\n")
-		// TODO: need some more attributes here...
-		// for _, Import := range doc.Imports {
-		// 	importstatements := strings.Split(Import, " ")
-		// 	if len(importstatements) == 1 {
-		// 		code += fmt.Sprintf("  \"%s\"\n", Import)
-		// 	} else {
-		// 		code += fmt.Sprintf("  %s \"%s\"\n", importstatements[0], importstatements[1])
-		// 	}
-
-		// }
-		// Add some static DB imports which we require
-		code += fmt.Sprintf("  \"database/sql\"\n")
-		code += fmt.Sprintf("  \"fmt\"\n")
-		code += fmt.Sprintf("  \"log\"\n")
-		code += fmt.Sprintf("  \"errors\"\n")
-		//		code += fmt.Sprintf("  uuid \"github.com/satori/go.uuid\"\n")
-		code += fmt.Sprintf("  // Need initialization\n")               // Ok, so I hardcoded this...
-		code += fmt.Sprintf("  _ \"github.com/go-sql-driver/mysql\"\n") // Ok, so I hardcoded this...
-		code += fmt.Sprintf(")\n")
-	}
-
-	code += fmt.Sprintf("//\n")
-	code += fmt.Sprintf("// this code is generated by the modelgenerator\n")
-	code += fmt.Sprintf("// data model source = %s\n", options.Filename)
-	code += fmt.Sprintf("//\n")
-	code += fmt.Sprintf("\n")
+func isPersistenceFile(name string) bool {
+	return name == "persistence_base.go" || strings.HasSuffix(name, "_persistence.go")
+}
 
-	code += fmt.Sprintf("var globalDataBase *sql.DB\n")
+// generateFile formats a file of a split output, the imports not used by the file are dropped by formatting
+func generateFile(dir string, name string, code string) (common.GeneratedFile, error) {
+	code, err := formatGenerated(filepath.Join(dir, name), code)
+	return common.GeneratedFile{Name: name, Code: code}, err
+}
 
+// prepare loads the templates and returns the classes to generate persistence for. The fetch functions of all classes
+// are known before any code is generated, the navigation helpers of a class fetch through the functions of others.
+func (generator *CrudGenerator) prepare(model *common.Model, options *common.Options) ([]*common.Type, error) {
+	templates, err := loadTemplates(options)
+	if err != nil {
+		return nil, err
+	}
+	generator.templates = templates
+	generator.fetchFunctions = make(map[string]string)
+
+	defines := []*common.Type{}
+	for _, define := range model.Types {
+		if define.SkipPersistance == true {
+			fmt.Printf("Skipping: %s\n", define.Name)
+			continue
+		}
+		if !options.IsPersistenceClass(define.Name) {
+			continue
+		}
+		switch define.Type {
+		case "class":
+			generator.fetchFunctions[define.Name] = fetchFunctionName(define)
+			defines = append(defines, define)
+		case "enum": // No code for this one!!
+		default:
+			log.Fatalf("[XMLDefine::generatePersistenceCode] Error, can't generate code for type '%s'\n", define.Type)
+		}
+	}
+	return defines, nil
+}
+
+// persistenceSchema returns the DB schema of the model, the 'dbschema' or the namespace with the table prefix or the schema of <dbcontrol>
+func persistenceSchema(model *common.Model, options *common.Options) string {
+	doc := model.Doc
 	schemaName := doc.DBSchema
 	if len(schemaName) < 1 {
 		schemaName = doc.Namespace
 	}
-
-	code += fmt.Sprintf("// Constants for DB connectivity\n")
-	code += fmt.Sprintf("const (\n")
-	//	code += fmt.Sprintf("   DB_NAME        = \"gnilk\"\n")
-	code += fmt.Sprintf("   DB_USER        = \"%s\"\n", doc.DBControl.User)
-	code += fmt.Sprintf("   DB_PASSWORD    = \"%s\"\n", doc.DBControl.Password)
-	if (len(doc.DBControl.Schema) < 1) {
-		code += fmt.Sprintf("   DB_SCHEMA      = \"%s%s\"\n", options.DBTablePrefix, schemaName)
+	if len(doc.DBControl.Schema) < 1 {
+		schemaName = options.DBTablePrefix + schemaName
 	} else {
-		code += fmt.Sprintf("   DB_SCHEMA      = \"%s\"\n", doc.DBControl.Schema);
+		schemaName = doc.DBControl.Schema
 	}
-	code += fmt.Sprintf("   DB_HOST_MYSQL  = \"%s\"\n", doc.DBControl.Host)
-	code += fmt.Sprintf("   DB_NAME_MYSQL  = \"%s\"\n", doc.DBControl.DBName)
-	code += fmt.Sprintf(")\n")
-
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("type Persistence struct {\n")
-	code += fmt.Sprintf("  db *sql.DB\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("\n")
-
-	// Private code - same for all persistance layers
-	// This could be put in a template - it's the same for all DB layers
-	code += fmt.Sprintf("func initMySQL() error {\n")
-	code += fmt.Sprintf("  constr := fmt.Sprintf(\"%%s:%%s@/%%s?parseTime=true\",\n")
-	code += fmt.Sprintf("       DB_USER,\n")
-	code += fmt.Sprintf("       DB_PASSWORD,\n")
-	code += fmt.Sprintf("       DB_NAME_MYSQL)\n")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  db, err := sql.Open(\"mysql\", constr)\n")
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("    log.Panic(err)\n")
-	code += fmt.Sprintf("    return err\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  globalDataBase = db\n")
-	code += fmt.Sprintf("  return nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("func globalInitDb() error {\n")
-	code += fmt.Sprintf("  return initMySQL()\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("func NewPersistence() (*Persistence, error) {\n")
-	code += fmt.Sprintf("  if globalDataBase == nil {\n")
-	code += fmt.Sprintf("    err := globalInitDb()\n")
-	code += fmt.Sprintf("    if err != nil {\n")
-	code += fmt.Sprintf("      log.Println(\"globalInitDb, %%v\", err)\n")
-	code += fmt.Sprintf("      return nil, err\n")
-	code += fmt.Sprintf("    }\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  p := &Persistence{db: globalDataBase}\n")
-	code += fmt.Sprintf("  return p, nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
+	return schemaName
+}
 
-	return code
+// generatePersistenceHeader generates the header of the persistence file with the DB connection
+func (generator *CrudGenerator) generatePersistenceHeader(model *common.Model, options *common.Options) (string, error) {
+	return generator.templates.Execute("persistenceHeader", generator.persistenceHeaderData(model, options, true))
 }
 
-func generatePersistenceCodeForDefine(define *common.XMLDefine, options *common.Options, className string, converters bool) string {
+// generatePersistenceFileHeader generates the header of a class file of a split output (-s)
+func (generator *CrudGenerator) generatePersistenceFileHeader(model *common.Model, options *common.Options) (string, error) {
+	code, err := generator.templates.Execute("persistenceFileHeader", generator.persistenceHeaderData(model, options, false))
+	return code + "\n", err
+}
 
-	// Check if class name matches - perhaps use regexp here..
-	if strings.Compare(className, "-") != 0 {
-		if strings.Compare(className, define.Name) != 0 {
-			return ""
-		}
+func (generator *CrudGenerator) persistenceHeaderData(model *common.Model, options *common.Options, driver bool) templateHeader {
+	return templateHeader{
+		Package: packageName(model, options),
+		Source:  options.Filename,
+		// Key types are used as typed parameters, include the packages they refer to
+		Imports: keyTypeImports(model),
+		Doc:     model.Doc,
+		Schema:  persistenceSchema(model, options),
+		Driver:  driver,
 	}
+}
 
+func (generator *CrudGenerator) generatePersistenceCodeForDefine(define *common.Type, options *common.Options) (string, error) {
 	log.Printf("Generating persistence for class: %s\n", define.Name)
 
-	//	code += fmt.Sprintf("   DB_SCHEMA      = \"%s%s\"\n", options.DBTablePrefix, schemaName)
-
-	dbSchemaName := fmt.Sprintf("%s%s", options.DBTablePrefix, strings.ToLower(define.Name))
-	if define.DBSchema != "" {
-		dbSchemaName = fmt.Sprintf("%s%s", options.DBTablePrefix, define.DBSchema)
+	// TODO: Check if we should support this... not quite sure..
+	if !isJoinedClass(define) && len(define.Table().ValueFields()) == 0 {
+		log.Printf("Class: '%s' has only key fields or no field!! - only Retrieve and Delete are generated, set attribute 'nopersist=\"true\"' on class to generate lagnuage definition but no persistence code.", define.Name)
 	}
+	return generator.templates.Execute("persistence", templateType{
+		Type:    define,
+		Options: options,
+		Fetch:   generator.fetchFunctions[define.Name],
+	})
+}
 
-	code := ""
-	code += fmt.Sprintf("const DB_SCHEMA_%s = \"%s\"\n", strings.ToUpper(define.Name), dbSchemaName)
-
-	//fmt.Printf("Generating code for type='%s', named='%s'\n", define.Type, define.Name)
-	switch define.Type {
-	case "class":
-		code += generatePersistenceCreateCode(define)
-		code += generatePersistenceFetchCode(define)
-		code += generatePersistenceRetrieveCode(define)
-		code += generatePersistenceUpdateCode(define)
-		code += generatePersistenceDeleteCode(define)
-		// if converters {
-		// 	code += define.generateClassConverters()
-		// }
-		break
-	case "enum": // No code for this one!!
-		return ""
-	default:
-		log.Fatalf("[XMLDefine::generatePersistenceCode] Error, can't generate code for type '%s'\n", define.Type)
-		break
-	}
-
-	// Create postfix on fetch query function
-	createRetrieveFuncPostfix = true
-
-	return code
+func getSchemaName(define *common.Type) string {
+	return (fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name)))
 }
 
-func generateErrorCheck() string {
-	code := ""
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("    return err\n")
-	code += fmt.Sprintf("  }\n")
-	return code
+// dbSchemaName returns the name of the table of the define, the 'dbschema' attribute overrides the class name
+func dbSchemaName(define *common.Type, options *common.Options) string {
+	if define.DBSchema != "" {
+		return fmt.Sprintf("%s%s", options.DBTablePrefix, define.DBSchema)
+	}
+	return fmt.Sprintf("%s%s", options.DBTablePrefix, strings.ToLower(define.Name))
 }
 
-func generateErrorCheckUserReturn(retval string) string {
-	code := ""
-	code += fmt.Sprintf("  if err != nil {\n")
-	code += fmt.Sprintf("    return %s, err\n", retval)
-	code += fmt.Sprintf("  }\n")
-	return code
+// tableRef returns the table name to put in a quoted query, the constant of the class or the name of a parent table
+func tableRef(define *common.Type, table *common.Table, options *common.Options) string {
+	if table.Type == define {
+		return fmt.Sprintf("\" + %s + \"", getSchemaName(define))
+	}
+	return dbSchemaName(table.Type, options)
 }
 
-// func lastPersistedMethodName(define *common.XMLDefine) string {
-// 	lastName := ""
-// 	for _, f := range define.Methods {
-// 		if f.noPersist == true {
-// 			continue
-// 		}
-// 		lastName = f.Name
-// 	}
-// 	return lastName
+// isJoinedClass returns true if the fields of the class are stored in more than one table (joined inheritance)
+func isJoinedClass(define *common.Type) bool {
+	return len(define.Tables()) > 1
+}
 
-// }
+// selectFields returns the fields in the order they are selected and scanned, the key columns of joined tables are only selected once
+func selectFields(define *common.Type) []*common.Field {
+	fields := []*common.Field{}
+	for _, table := range define.Tables() {
+		fields = append(fields, table.DeclaredFields()...)
+	}
+	return fields
+}
 
-func lastFieldName(define *common.XMLDefine) string {
-	lastName := ""
-	for _, f := range define.Fields {
-		if f.SkipPersistance == true {
-			continue
-		}
-		lastName = f.Name
+// selectColumns returns the columns of the select query of a joined class, see columnRef
+func selectColumns(define *common.Type) []string {
+	columns := []string{}
+	for _, field := range selectFields(define) {
+		columns = append(columns, columnRef(define, field))
 	}
-	return lastName
+	return columns
 }
 
-func getSchemaName(define *common.XMLDefine) string {
-	return (fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name)))
+// columnRef returns the column of a field in the select query, qualified with the table alias for joined classes
+func columnRef(define *common.Type, field *common.Field) string {
+	if !isJoinedClass(define) {
+		return strings.ToLower(field.Name)
+	}
+	for i, table := range define.Tables() {
+		for _, column := range table.DeclaredFields() {
+			if column == field {
+				return fmt.Sprintf("t%d.%s", i, strings.ToLower(field.Name))
+			}
+		}
+	}
+	return fmt.Sprintf("t0.%s", strings.ToLower(field.Name))
 }
 
-func generatePersistenceReadWriteVarList(define *common.XMLDefine, varName string, skipAutoID bool) string {
+// fromClause returns the tables of a joined class to put in a quoted query, like 'animal t0 JOIN " + DB_SCHEMA_DOG + " t1 ON t1.id=t0.id'
+func fromClause(define *common.Type, options *common.Options) string {
 	code := ""
-
-	lastName := lastFieldName(define)
-
-	for _, f := range define.Fields {
-		if f.SkipPersistance == true {
-			continue
-		}
-		if (f.DBAutoID == true) && (skipAutoID == true) {
+	for i, table := range define.Tables() {
+		if i == 0 {
+			code += fmt.Sprintf("%s t0", tableRef(define, table, options))
 			continue
 		}
-		if strings.Compare(f.Name, lastName) != 0 {
-			code += fmt.Sprintf("      %s.%s,\n", varName, f.Name)
-		} else {
-			code += fmt.Sprintf("      %s.%s)\n", varName, f.Name)
+		conditions := []string{}
+		for _, key := range table.Keys {
+			conditions = append(conditions, fmt.Sprintf("t%d.%s=t0.%s", i, strings.ToLower(key.Name), strings.ToLower(key.Name)))
 		}
+		code += fmt.Sprintf(" JOIN %s t%d ON %s", tableRef(define, table, options), i, strings.Join(conditions, " AND "))
 	}
-
-	// TODO: Shit should not work on methods..
-	// for _, f := range define.Methods {
-	// 	if f.NoPersist == true {
-	// 		continue
-	// 	}
-	// 	if (f.DBAutoID == true) && (skipAutoID == true) {
-	// 		continue
-	// 	}
-	// 	if strings.Compare(f.Name, lastName) != 0 {
-	// 		code += fmt.Sprintf("      %s.%s,\n", varName, f.Name)
-	// 	} else {
-	// 		code += fmt.Sprintf("      %s.%s)\n", varName, f.Name)
-	// 	}
-	// }
-	code += fmt.Sprintf("\n")
 	return code
 }
 
-func generatePersistenceCreateCode(define *common.XMLDefine) string {
-
-	code := ""
-
-	code += fmt.Sprintf("var ErrNoSuch%s = errors.New(\"No such %s\")\n", define.Name, define.Name)
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("const createUpdateVariables%s = \"", define.Name)
-
-	if len(define.Fields) == 0 {
-		log.Println("Define has no fields!!!!")
-		log.Println(define)
-	}
-
-	//primaryFieldName := strings.ToLower(define.Name) + "id"
-	primaryFieldName := strings.ToLower(define.Fields[0].Name)
-	primaryIsAutoID := define.Fields[0].DBAutoID
-
-	//lastName := define.lastPersistedMethodName()
-
-	haveAtleastOneField := false
-	for _, f := range define.Fields {
-		if f.SkipPersistance == true {
+// keyTypeImports returns the imports of the packages referred to by the Go types of primary key fields
+func keyTypeImports(model *common.Model) []templateImport {
+	qualifiers := make(map[string]bool)
+	for _, define := range model.Types {
+		if !define.IsClass() || define.SkipPersistance {
 			continue
 		}
-		dbFieldName := strings.ToLower(f.Name)
-		if strings.Compare(primaryFieldName, dbFieldName) != 0 {
-			code += fmt.Sprintf("%s=?,", dbFieldName)
-			haveAtleastOneField = true
+		for _, key := range define.Keys {
+			goType := key.MappedType(common.LangGo)
+			if idx := strings.Index(goType, "."); idx > 0 {
+				qualifiers[strings.TrimLeft(goType[:idx], "[]*")] = true
+			}
 		}
 	}
 
-	// TODO: Check if we should support this... not quite sure..
-	if !haveAtleastOneField {
-		log.Printf("Class: '%s' has only one field or no field!! - this won't work, set attribute 'nonpersist=\"true\"' on class to generate lagnuage definition but no persistence code.", define.Name)
+	imports := []templateImport{}
+	for _, Import := range templateImports(model.Doc.Imports) {
+		if (Import.Alias == "" && qualifiers[path.Base(Import.Path)]) || (Import.Alias != "" && qualifiers[Import.Alias]) {
+			imports = append(imports, Import)
+		}
 	}
-
-	code = code[:len(code)-1]
-	code += fmt.Sprintf("\"\n")
-	code += fmt.Sprintf("\n")
-
-	schemaName := getSchemaName(define) //fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name))
-
-	methodName := fmt.Sprintf("Create%s", define.Name)
-	code += fmt.Sprintf("// %s creates a record in the DB\n", methodName)
-	code += fmt.Sprintf("func (p* Persistence) %s(obj *%s) error {\n", methodName, define.Name)
-	if primaryIsAutoID {
-		// If autoid is enabled for the primary field we remove it from insert
-		code += fmt.Sprintf("  stmt, err := p.db.Prepare(\"INSERT \"+%s+\" SET \"+createUpdateVariables%s)\n", schemaName, define.Name)
-	} else {
-		// autoid is not enabled, so we insert
-		code += fmt.Sprintf("  stmt, err := p.db.Prepare(\"INSERT \"+%s+\" SET %s=?,\"+createUpdateVariables%s)\n", schemaName, primaryFieldName, define.Name)
-	}
-	code += fmt.Sprintf("  _, err = stmt.Exec(\n")
-	//log.Println("lastName: ", lastName)
-	code += generatePersistenceReadWriteVarList(define, "obj", true)
-	code += generateErrorCheck()
-	code += fmt.Sprintf("  return nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	return code
+	return imports
 }
 
-func generatePersistenceFetchCode(define *common.XMLDefine) string {
-	code := ""
-
-	if createRetrieveFuncPostfix == false {
-		code += fmt.Sprintf("func (p* Persistence) fetchFromQueryString(queryString string) ([]%s, error) {\n", define.Name)
-	} else {
-		code += fmt.Sprintf("func (p* Persistence) fetchFromQueryString%s(queryString string) ([]%s, error) {\n", define.Name, define.Name)
+// keyFields returns the persisted primary key fields of the define
+func keyFields(define *common.Type, skipAutoID bool) []*common.Field {
+	fields := []*common.Field{}
+	for _, key := range define.Keys {
+		if (key.DBAutoID == true) && (skipAutoID == true) {
+			continue
+		}
+		fields = append(fields, key)
 	}
-	code += fmt.Sprintf("  rows,err := p.db.Query(queryString)\n")
-	code += generateErrorCheckUserReturn("nil")
-
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  list := make([]%s,0,0)\n", define.Name)
-	code += fmt.Sprintf("\n")
-
-	code += fmt.Sprintf("  for rows.Next() {\n")
-	code += fmt.Sprintf("    res := %s{}\n", define.Name)
-	code += fmt.Sprintf("    err := rows.Scan(\n")
-	code += generatePersistenceReadWriteVarList(define, "&res", false)
-	code += generateErrorCheckUserReturn("nil")
-	code += fmt.Sprintf("    list = append(list, res)\n")
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("  return list, nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-
-	return code
+	return fields
 }
-func generatePersistenceRetrieveCode(define *common.XMLDefine) string {
-	code := ""
 
-	//fieldname := strings.ToLower(define.Name) + "id"
-	fieldname := strings.ToLower(define.Fields[0].Name)
-
-	schemaName := getSchemaName(define) // fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name))
+// reservedParamNames are the receiver, the locals and the packages used by the generated persistence functions
+var reservedParamNames = map[string]bool{
+	"p": true, "obj": true, "err": true, "stmt": true, "result": true, "queryString": true, "list": true,
+	"affected": true, "tx": true, "rows": true, "log": true, "fmt": true, "sql": true, "errors": true,
+}
 
-	methodName := fmt.Sprintf("Retrieve%sFromID", define.Name)
-	code += fmt.Sprintf("// %s Retrieves a single record in the DB matching supplied ID\n", methodName)
-	code += fmt.Sprintf("// ErrNoSuch%s is returned if no record is found\n", define.Name)
-	code += fmt.Sprintf("func (p *Persistence) %s(ID string) (*%s, error) {\n", methodName, define.Name)
-	code += fmt.Sprintf("  queryString := fmt.Sprintf(\"SELECT * FROM %%s WHERE %s='%%s'\",%s, ID)\n", fieldname, schemaName)
+// keyParamName returns the name of the function parameter for a key field, 'UserID' becomes 'userID', 'ID' becomes 'id'.
+// Keywords and the names used by the generated code get a suffix, 'Result' becomes 'resultValue'
+func keyParamName(field *common.Field) string {
+	name := common.CamelCase(field.Name)
+	if token.IsKeyword(name) || reservedParamNames[name] {
+		name = name + "Value"
+	}
+	return name
+}
 
-	if createRetrieveFuncPostfix == false {
-		code += fmt.Sprintf("  result, err := p.fetchFromQueryString(queryString)\n")
-	} else {
-		code += fmt.Sprintf("  result, err := p.fetchFromQueryString%s(queryString)\n", define.Name)
+// keyParamList returns the typed parameter declaration for the primary key, like 'userID uuid.UUID, orderID int'
+func keyParamList(define *common.Type) string {
+	params := []string{}
+	for _, key := range define.Keys {
+		params = append(params, fmt.Sprintf("%s %s", keyParamName(key), key.MappedType(common.LangGo)))
 	}
-	code += fmt.Sprintf("\n")
-	code += generateErrorCheckUserReturn("nil")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  if len(result) == 0 {\n")
-	code += fmt.Sprintf("    log.Println(\"No %s found for id: %%s\", ID)\n", define.Name)
-	code += fmt.Sprintf("    return nil, ErrNoSuch%s\n", define.Name)
-	code += fmt.Sprintf("  }\n")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  return &result[0],nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
+	return strings.Join(params, ", ")
+}
 
-	return code
+// keyArgList returns the key parameter names, as used when passing the key to a query
+func keyArgList(define *common.Type) string {
+	args := []string{}
+	for _, key := range define.Keys {
+		args = append(args, keyParamName(key))
+	}
+	return strings.Join(args, ", ")
 }
-func generatePersistenceUpdateCode(define *common.XMLDefine) string {
-	code := ""
-	//fieldname := strings.ToLower(define.Name) + "id"
-	fieldname := strings.ToLower(define.Fields[0].Name)
 
-	schemaName := getSchemaName(define) //fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name))
+// keyFormat returns the format of the key in a log message, like '%v,%v'
+func keyFormat(define *common.Type) string {
+	return strings.TrimSuffix(strings.Repeat("%v,", len(define.Keys)), ",")
+}
 
-	code += fmt.Sprintf("var updateQuery%s = \"UPDATE \" + %s + \" SET \" + createUpdateVariables%s + \" WHERE %s=?\"\n", define.Name, schemaName, define.Name, fieldname)
+// keyWhereClause returns the WHERE clause matching the primary key, like 'userid=? AND orderid=?'
+func keyWhereClause(define *common.Type) string {
+	conditions := []string{}
+	for _, key := range define.Keys {
+		conditions = append(conditions, fmt.Sprintf("%s=?", strings.ToLower(key.Name)))
+	}
+	return strings.Join(conditions, " AND ")
+}
 
-	methodName := fmt.Sprintf("Update%s", define.Name)
-	code += fmt.Sprintf("// %s Updates the structure in the db\n", methodName)
-	code += fmt.Sprintf("func (p *Persistence) %s(obj *%s) error {\n", methodName, define.Name)
-	code += fmt.Sprintf("  stmt, err := p.db.Prepare(updateQuery%s)\n", define.Name)
-	code += generateErrorCheck()
-	code += fmt.Sprintf("  _, err = stmt.Exec(\n")
+// selectKeyWhereClause returns the WHERE clause matching the primary key in the select query, see columnRef
+func selectKeyWhereClause(define *common.Type) string {
+	conditions := []string{}
+	for _, key := range define.Keys {
+		conditions = append(conditions, fmt.Sprintf("%s=?", columnRef(define, key)))
+	}
+	return strings.Join(conditions, " AND ")
+}
 
-	//mainKeyField := fmt.Sprintf("%sID", define.Name)
-	mainKeyField := define.Fields[0].Name
+// assignmentList returns the column assignments for an INSERT or UPDATE, like 'name=?,age=?'
+func assignmentList(fields []*common.Field) string {
+	assignments := []string{}
+	for _, f := range fields {
+		assignments = append(assignments, fmt.Sprintf("%s=?", strings.ToLower(f.Name)))
+	}
+	return strings.Join(assignments, ",")
+}
 
-	for _, f := range define.Fields {
-		if f.SkipPersistance == true {
-			continue
-		}
-		if strings.Compare(f.Name, mainKeyField) != 0 {
-			code += fmt.Sprintf("    obj.%s,\n", f.Name)
+// generateFieldVarList generates the argument list for Exec, closing the call after the last field
+func generateFieldVarList(fields []*common.Field, varName string) string {
+	code := ""
+	for i, f := range fields {
+		if i < len(fields)-1 {
+			code += fmt.Sprintf("      %s.%s,\n", varName, f.Name)
+		} else {
+			code += fmt.Sprintf("      %s.%s)\n", varName, f.Name)
 		}
 	}
-	code += fmt.Sprintf("    obj.%s)\n", mainKeyField)
-	code += fmt.Sprintf("\n")
-	code += generateErrorCheck()
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  return nil\n")
-	code += fmt.Sprintf("}\n")
 	code += fmt.Sprintf("\n")
 	return code
 }
 
-func generatePersistenceDeleteCode(define *common.XMLDefine) string {
-	code := ""
-
-	//fieldname := strings.ToLower(define.Name) + "id"
-	fieldname := strings.ToLower(define.Fields[0].Name)
-	schemaName := getSchemaName(define) //fmt.Sprintf("DB_SCHEMA_%s", strings.ToUpper(define.Name))
-	mainKeyField := fmt.Sprintf("%sID", define.Name)
+// insertKeys returns the key fields inserted in a table of a joined class, the base table generates autoid keys
+func insertKeys(index int, table *common.Table) []*common.Field {
+	if index > 0 {
+		return table.Keys
+	}
+	keys := []*common.Field{}
+	for _, key := range table.Keys {
+		if !key.DBAutoID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
 
-	code += fmt.Sprintf("var deleteQuery%s = \"DELETE FROM \" + %s + \" WHERE %s=?\"\n", define.Name, schemaName, fieldname)
-	code += fmt.Sprintf("\n")
-	methodName := fmt.Sprintf("Delete%s", define.Name)
-	code += fmt.Sprintf("// %s Deletes the structure in the db\n", methodName)
-	code += fmt.Sprintf("func (p *Persistence) %s(%s string) error {\n", methodName, mainKeyField)
+// autoIDKeys returns the key fields generated by a table of a joined class, set in the object after the insert
+func autoIDKeys(index int, table *common.Table) []*common.Field {
+	keys := []*common.Field{}
+	if index > 0 {
+		return keys
+	}
+	for _, key := range table.Keys {
+		if key.DBAutoID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
 
-	code += fmt.Sprintf("  stmt, err := p.db.Prepare(deleteQuery%s)\n", define.Name)
-	code += generateErrorCheck()
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  result, err := stmt.Exec(%s)\n", mainKeyField)
-	code += generateErrorCheck()
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  affected, _ := result.RowsAffected()\n")
-	code += fmt.Sprintf("  if affected == 0 {\n")
-	code += fmt.Sprintf("    return ErrNoSuch%s\n", define.Name)
-	code += fmt.Sprintf("  }")
-	code += fmt.Sprintf("\n")
-	code += fmt.Sprintf("  return nil\n")
-	code += fmt.Sprintf("}\n")
-	code += fmt.Sprintf("\n")
-	return code
+// fetchFunctionName returns the name of the fetch function for the define, qualified with the class name as GO doesn't
+// support polymorphic functions
+func fetchFunctionName(define *common.Type) string {
+	return fmt.Sprintf("fetchFromQueryString%s", define.Name)
 }
@@ -0,0 +1,96 @@
+package golang
+
+//
+// Generates navigation helpers for relations between classes
+// Helpers are only generated when the persistence for both sides of the relation is generated,
+// the code is in the "referenceHelper", "oneToManyHelpers" and "manyToManyHelpers" templates
+//
+
+import (
+	"fmt"
+	"log"
+	"modelgenerator/common"
+	"strings"
+)
+
+// generateRelationCode generates the navigation helpers of a class, the classes on the other side need persistence as well
+func (generator *CrudGenerator) generateRelationCode(define *common.Type, options *common.Options) (string, error) {
+	code := ""
+	fetch, ok := generator.fetchFunctions[define.Name]
+	if !ok {
+		return code, nil
+	}
+	data := templateType{Type: define, Options: options, Fetch: fetch}
+	for _, field := range define.Fields {
+		if field.References == "" || !field.IsPersisted() {
+			continue
+		}
+		if field.Referenced == nil {
+			log.Printf("!WARNING!: '%s' %s\n", field, field.ResolveError)
+			continue
+		}
+		helperCode, err := generator.templates.Execute("referenceHelper", templateRelation{templateType: data, Field: field})
+		if err != nil {
+			return "", err
+		}
+		code += helperCode
+	}
+	for _, field := range define.Fields {
+		if field.IsRemoved() || (field.Relation != common.RelationOneToMany && field.Relation != common.RelationManyToMany) {
+			continue
+		}
+		if field.Related == nil {
+			log.Printf("!WARNING!: '%s' %s\n", field, field.ResolveError)
+			continue
+		}
+		if _, ok := generator.fetchFunctions[field.Related.Target.Name]; !ok {
+			continue
+		}
+		name := "manyToManyHelpers"
+		if field.Relation == common.RelationOneToMany {
+			if field.Related.MappedBy.Referenced == nil {
+				continue
+			}
+			name = "oneToManyHelpers"
+		}
+		helperCode, err := generator.templates.Execute(name, templateRelation{templateType: data, Field: field})
+		if err != nil {
+			return "", err
+		}
+		code += helperCode
+	}
+	return code, nil
+}
+
+// pluralize returns the (english) plural of a class name, 'Order' becomes 'Orders', 'Category' becomes 'Categories'
+func pluralize(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
+// referenceHelperName returns the name of the Retrieve function for a referencing field, like 'RetrieveOrdersForUser'.
+// If the class has several fields referencing the same class the field name is used instead, like 'RetrieveOrdersByCreatedBy'.
+func referenceHelperName(field *common.Field) string {
+	define, refDefine := field.Owner, field.Referenced.Owner
+	if len(define.ReferencesTo(refDefine)) > 1 || refDefine == define {
+		return fmt.Sprintf("Retrieve%sBy%s", pluralize(define.Name), field.Name)
+	}
+	return fmt.Sprintf("Retrieve%sFor%s", pluralize(define.Name), refDefine.Name)
+}
+
+// joinChildParam returns the parameter name of the key of the related class in the join table helpers, qualified with
+// the field name if both keys have the same name
+func joinChildParam(field *common.Field) string {
+	parentParam, childParam := keyParamName(field.Related.OwnerKey), keyParamName(field.Related.TargetKey)
+	if childParam == parentParam {
+		childParam = strings.ToLower(field.Name[:1]) + field.Name[1:] + field.Related.TargetKey.Name
+	}
+	return childParam
+}
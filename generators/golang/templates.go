@@ -0,0 +1,152 @@
+package golang
+
+//
+// Templates of the GO generators (templates/*.tmpl), the model and the persistence templates are one set so an
+// override can use the helpers and the error handling of both
+//
+
+import (
+	"embed"
+	"fmt"
+	"modelgenerator/common"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templateType is the data of the templates generating code for a define, '.Type' is the define itself
+type templateType struct {
+	*common.Type
+	Options *common.Options
+	// Fetch is the fetch function of the class, set for the persistence templates
+	Fetch string
+}
+
+// templateRelation is the data of the relation templates, '.Field' is the relation field of the class
+type templateRelation struct {
+	templateType
+	Field *common.Field
+}
+
+// templateField is the data of the field validation templates, '.Field' is the field itself
+type templateField struct {
+	*common.Field
+	Options *common.Options
+}
+
+// IsPointerValue returns true if the value of the field is a pointer, see goType
+func (field templateField) IsPointerValue() bool {
+	return !field.IsList && (field.IsPointer || field.IsNullable())
+}
+
+// templateHeader is the data of the 'header', 'persistenceHeader' and 'persistenceFileHeader' templates
+type templateHeader struct {
+	Package string
+	Source  string
+	Imports []templateImport
+	Doc     *common.XMLDoc
+	Schema  string
+	// Driver is set for the file opening the DB connection, it imports the SQL driver
+	Driver bool
+}
+
+// templateImport is an import statement, the alias is empty for a plain import
+type templateImport struct {
+	Alias string
+	Path  string
+}
+
+// loadTemplates returns the GO templates, overridden by the templates in the template directory of the options
+func loadTemplates(options *common.Options) (*common.Templates, error) {
+	templates, err := common.LoadTemplates(common.LangGo, templateFiles, options, templateFuncs())
+	if err != nil {
+		return nil, fmt.Errorf("can't load the GO templates: %w", err)
+	}
+	return templates, nil
+}
+
+// executeTemplates returns the output of the templates one after the other
+func executeTemplates(templates *common.Templates, names []string, data interface{}) (string, error) {
+	code := ""
+	for _, name := range names {
+		templateCode, err := templates.Execute(name, data)
+		if err != nil {
+			return "", err
+		}
+		code += templateCode
+	}
+	return code, nil
+}
+
+// templateImports converts imports written as in the model ('path' or 'alias path')
+func templateImports(imports []common.XMLImport) []templateImport {
+	result := []templateImport{}
+	for _, Import := range imports {
+		importstatements := strings.Split(Import.Package, " ")
+		if len(importstatements) == 1 {
+			result = append(result, templateImport{Path: Import.Package})
+		} else {
+			result = append(result, templateImport{Alias: importstatements[0], Path: importstatements[1]})
+		}
+	}
+	return result
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"doc":      docComment,
+		"fieldDoc": fieldDocumentation,
+		"goType":   goType,
+		"goTags":   goTags,
+		"goMappedType": func(field *common.Field) string {
+			return field.MappedType(common.LangGo)
+		},
+		"concat": func(lists ...[]*common.Field) []*common.Field {
+			fields := []*common.Field{}
+			for _, list := range lists {
+				fields = append(fields, list...)
+			}
+			return fields
+		},
+		"schemaConst":          getSchemaName,
+		"dbSchemaName":         dbSchemaName,
+		"tableRef":             tableRef,
+		"fromClause":           fromClause,
+		"isJoined":             isJoinedClass,
+		"selectFields":         selectFields,
+		"selectColumns":        selectColumns,
+		"keyFields":            keyFields,
+		"insertKeys":           insertKeys,
+		"autoIDKeys":           autoIDKeys,
+		"keyParamList":         keyParamList,
+		"keyArgList":           keyArgList,
+		"keyFormat":            keyFormat,
+		"keyWhereClause":       keyWhereClause,
+		"selectKeyWhereClause": selectKeyWhereClause,
+		"assignmentList":       assignmentList,
+		"fieldVarList":         generateFieldVarList,
+		"referenceHelper":      referenceHelperName,
+		"keyParam":             keyParamName,
+		"columnRef":            columnRef,
+		"fetchFunction":        fetchFunctionName,
+		"joinTableName":        getDBJoinTableName,
+		"joinChildParam":       joinChildParam,
+		"validationField": func(field *common.Field, options *common.Options) templateField {
+			return templateField{Field: field, Options: options}
+		},
+		"requiredCheck": requiredCheckKind,
+		"isNumeric": func(field *common.Field) bool {
+			return common.IsNumericGoType(field.MappedType(common.LangGo))
+		},
+		"maxLen": func(field *common.Field, options *common.Options) int {
+			return field.EffectiveMaxLen(options.CurrentDoc)
+		},
+		"patternVar":    patternVarName,
+		"validatedType": validatedType,
+		"violation":     violation,
+		"quote":         strconv.Quote,
+	}
+}
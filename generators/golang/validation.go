@@ -0,0 +1,73 @@
+package golang
+
+//
+// Generates Validate() methods from the field constraints
+// required, min/max, minlen/maxlen (defaults to the field size for strings), pattern and enum membership.
+// The code is in the "validationErrorType" and "validate" templates, these are the helpers.
+//
+
+import (
+	"fmt"
+	"modelgenerator/common"
+	"strconv"
+)
+
+// addValidationImports adds the imports used by the generated validation code
+func (generator *CodeGenerator) addValidationImports(model *common.Model) {
+	generator.addImport("fmt")
+	generator.addImport("strings")
+	for _, define := range model.Types {
+		for _, field := range define.Fields {
+			if field.Pattern != "" && !field.IsList {
+				generator.addImport("regexp")
+			}
+			if !field.IsList && (field.MinLen != 0 || field.EffectiveMaxLen(model.Doc) != 0) {
+				generator.addImport("unicode/utf8")
+			}
+			if field.Required && requiredCheckKind(field) == "reflect" {
+				generator.addImport("reflect")
+			}
+		}
+	}
+}
+
+// requiredCheckKind returns how a required field is checked for a value
+func requiredCheckKind(field *common.Field) string {
+	goType := field.MappedType(common.LangGo)
+	switch {
+	case field.IsList:
+		return "len"
+	case field.IsPointer || field.IsNullable():
+		return "nil"
+	case goType == "string":
+		return "len"
+	case common.IsNumericGoType(goType):
+		return "zero"
+	case goType == "bool":
+		return ""
+	}
+	if field.IsEnum() {
+		// enums are checked for membership, the zero value is not a member
+		return ""
+	}
+	return "reflect"
+}
+
+// violation returns the statement adding a violation for the named field
+func violation(name string, message string) string {
+	return fmt.Sprintf("violations = append(violations, Violation{Field: path + \"%s\", Message: %s})", name, strconv.Quote(message))
+}
+
+// patternVarName returns the name of the compiled 'pattern' of a field
+func patternVarName(field *common.Field) string {
+	return fmt.Sprintf("pattern%s%s", field.Owner.Name, field.Name)
+}
+
+// validatedType returns the class or enum of a field checked by the validation, the checks of a referenced type are
+// unexported in its package, it is validated by its own Validate()
+func validatedType(field *common.Field) *common.Type {
+	if field.UserType == nil || field.UserType.IsExternal() {
+		return nil
+	}
+	return field.UserType
+}
@@ -3,7 +3,6 @@ package typescript
 import "modelgenerator/common"
 
 type CodeGenerator struct {
-	Methods []common.AccessMethod
 	Imports []common.XMLImport
 }
 
@@ -14,6 +13,15 @@ func CreateTSLanguage() common.Language {
 	return (common.Language)(&tslang)
 }
 
+func init() {
+	common.RegisterLanguage(common.LanguageInfo{
+		Name:        "ts",
+		Aliases:     []string{"typescript"},
+		Description: "TypeScript classes",
+		Create:      CreateTSLanguage,
+	})
+}
+
 func (lang *TSLangGenerators) GetModelGenerator() common.Generator {
 	return createTSLangGenerator()
 }
@@ -4,29 +4,36 @@ import (
 	"fmt"
 	"log"
 	"modelgenerator/common"
+	"strings"
 )
 
-func (generator *CodeGenerator) GenerateCode(doc common.XMLDoc, options *common.Options) string {
+func (generator *CodeGenerator) GenerateCode(model *common.Model, options *common.Options) (string, error) {
 	code := ""
 	if options.SplitInFiles == true {
 		log.Printf("Split In Files not supported!\n")
-		return code
+		return code, nil
 	}
 
 	code += fmt.Sprintf("//\n")
 	code += fmt.Sprintf("// This file has been generated by ModelGenerator - do NOT edit!\n")
 	code += fmt.Sprintf("//\n")
 
-	for _, define := range doc.Defines {
+	// Types of referenced documents are imported from the module generated for the referenced document
+	for _, refDoc := range model.UsedReferences() {
+		code += fmt.Sprintf("import { %s } from \"./%s\";\n", strings.Join(model.UsedTypesFrom(refDoc), ", "), refDoc.ModulePath())
+	}
+
+	// A class can only extend a class declared before it
+	for _, define := range model.SortTypesByInheritance() {
 		//log.Printf("Generate for define: %s\n", define.Name)
-		code += generator.generateHeaderCodeForDefine(&define, options)
+		code += generator.generateHeaderCodeForDefine(define, options)
 		//		code += generator.generateCode(&define, options)
 	}
 
-	return code
+	return code, nil
 }
 
-func (generator *CodeGenerator) generateHeaderCodeForDefine(define *common.XMLDefine, options *common.Options) string {
+func (generator *CodeGenerator) generateHeaderCodeForDefine(define *common.Type, options *common.Options) string {
 	code := ""
 
 	log.Printf("Generating code for type='%s', named='%s'\n", define.Type, define.Name)
@@ -46,19 +53,19 @@ func (generator *CodeGenerator) generateHeaderCodeForDefine(define *common.XMLDe
 
 }
 
-func (generator *CodeGenerator) generateClassCodeDefinition(define *common.XMLDefine, options *common.Options) string {
+func (generator *CodeGenerator) generateClassCodeDefinition(define *common.Type, options *common.Options) string {
 	code := ""
+	code += docComment(define.Documentation(), "")
 	// Begin class header
-	code += fmt.Sprintf("class %s", define.Name)
+	// Exported so other documents can reference the class
+	code += fmt.Sprintf("export class %s", define.Name)
 	if define.Inherits != "" {
-		code += fmt.Sprintf(" : public %s", define.Inherits)
+		code += fmt.Sprintf(" extends %s", define.Inherits)
 	}
 	code += fmt.Sprintf(" {\n") // end class header
-	// fields
-	code += fmt.Sprintf("public:\n")
 
 	for _, field := range define.Fields {
-		code += generator.fieldCode(options, &field)
+		code += generator.fieldCode(options, field)
 		//generator.methodFromField(define, field, field.TypeMapping(options.CurrentDoc.GOTypeMappings), field.IsList)
 	}
 
@@ -66,31 +73,59 @@ func (generator *CodeGenerator) generateClassCodeDefinition(define *common.XMLDe
 	return code
 }
 
-func (generator *CodeGenerator) fieldCode(options *common.Options, field *common.XMLDataTypeField) string {
+// fieldCode declares a field as 'Name: type;', a list is 'Name: type[];' and a nullable field 'Name: type | null;'
+func (generator *CodeGenerator) fieldCode(options *common.Options, field *common.Field) string {
 	code := ""
 
-	typePrefix := ""
+	lines := field.Documentation()
+	if note := field.DeprecationNote(); note != "" {
+		lines = append(lines, "@deprecated "+note)
+	}
+	code += docComment(lines, "    ")
+	typeName := field.MappedType(common.LangTypeScript)
 	if field.IsList {
-		typePrefix = typePrefix + "[]"
+		typeName = typeName + "[]"
 	}
-	if field.IsPointer {
-		typePrefix = typePrefix + "*"
+	if field.IsNullable() {
+		typeName = typeName + " | null"
 	}
-	code += fmt.Sprintf("    %s %s%s;\n", field.TypeMappingLang(options.CurrentDoc.AnyTypeMappings, "ts"), typePrefix, field.Name)
+	code += fmt.Sprintf("    %s: %s;\n", field.Name, typeName)
 
 	return code
 }
 
-func (generator *CodeGenerator) generateEnumCodeDefinition(define *common.XMLDefine, options *common.Options) string {
+func (generator *CodeGenerator) generateEnumCodeDefinition(define *common.Type, options *common.Options) string {
 	code := ""
+	code += docComment(define.Documentation(), "")
 	code += fmt.Sprintf("typedef enum {\n")
 	for _, Int := range define.Ints {
+		code += docComment(Int.Documentation(), "    ")
 		code += fmt.Sprintf("    %s = %d,\n", Int.Name, Int.Value)
 	}
 	code += fmt.Sprintf("} %s;\n\n", define.Name)
 	return code
 }
 
-// func (generator *CodeGenerator) generateCode(options *common.Options, define *common.XMLDefine) string {
+// func (generator *CodeGenerator) generateCode(options *common.Options, define *common.Type) string {
 // 	return ""
 // }
+
+// docComment returns the JSDoc comment for a 'description' or <doc>, empty if there is none
+func docComment(lines []string, indent string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	// The comment can't be closed by the text
+	for i := range lines {
+		lines[i] = strings.Replace(lines[i], "*/", "* /", -1)
+	}
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s/** %s */\n", indent, lines[0])
+	}
+	code := fmt.Sprintf("%s/**\n", indent)
+	for _, line := range lines {
+		code += strings.TrimRight(fmt.Sprintf("%s * %s", indent, line), " ") + "\n"
+	}
+	code += fmt.Sprintf("%s */\n", indent)
+	return code
+}
@@ -1,3 +1,5 @@
 module modelgenerator
 
 go 1.20
+
+require gopkg.in/yaml.v3 v3.0.1
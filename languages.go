@@ -0,0 +1,12 @@
+package main
+
+//
+// The languages compiled in, a language registers itself (see common.RegisterLanguage) when its package is imported.
+// In-house languages are added with a file importing their package the same way, no other change is needed.
+//
+
+import (
+	_ "modelgenerator/generators/cpp"
+	_ "modelgenerator/generators/golang"
+	_ "modelgenerator/generators/typescript"
+)
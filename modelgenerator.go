@@ -6,129 +6,141 @@ package main
 //
 
 import (
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"modelgenerator/common"
-	"modelgenerator/generators/cpp"
-	golang "modelgenerator/generators/golang"
-	"modelgenerator/generators/typescript"
 )
 
 const Name = "ModelGenerator"
 const Version = "2.2"
 
 //
-// Load's an XML document an preprocess (load and merge any include directive)
+// Load's an XML document an preprocess (load and merge any include directive, recursively)
 //
 func loadDocument(options *common.Options, Filename string) (common.XMLDoc, error) {
-	var doc common.XMLDoc
-
-	xmlData, err := ioutil.ReadFile(Filename)
-	if err != nil {
-		log.Println("Error while opening file: ", err)
-		return doc, err
-	}
-
-	err = xml.Unmarshal(xmlData, &doc)
+	doc, err := common.LoadDocument(options, Filename)
 	if err != nil {
-		log.Println("Error while unmarshalling XML:", err)
+		log.Println("Error while loading document:", err)
 		return doc, err
 	}
-	preprocessDocument(options, &doc)
 	return doc, nil
 }
 
-func preprocessDocument(options *common.Options, doc *common.XMLDoc) {
-	for _, include := range doc.Includes {
-		incPathName := filepath.Join(options.DocumentRootDirectory, include.Filename)
+//
+// Generate domain model for selected language
+//
+func generateLanguageModel(options *common.Options, model *common.Model, out *output) error {
 
-		if options.Verbose > 0 {
-			log.Printf("Including: %s\n", include.Filename)
-			log.Printf("Pathname: %s\n", incPathName)
+	codeGenerator := options.Language.GetModelGenerator()
+	if options.SplitInFiles {
+		fileGenerator, err := splitGenerator(codeGenerator, "model", options)
+		if err != nil {
+			return err
 		}
-		//options.DocumentRootDirectory = filepath.Dir(intputFilePath)
-
-		incDoc, err := loadDocument(options, incPathName)
+		files, err := fileGenerator.GenerateFiles(model, options)
 		if err != nil {
-			log.Fatalf("Unable to include file: %s (%s)\n", include.Filename, incPathName)
-			return
+			return err
 		}
-		include.Document = incDoc
-		mergeDocuments(doc, &incDoc)
-	}
-}
-
-func mergeDocuments(dst *common.XMLDoc, src *common.XMLDoc) *common.XMLDoc {
-	//
-	// Note: We don't merge includes!!!
-	//
-	dst.Imports = append(dst.Imports, src.Imports...)
-	dst.Defines = append(dst.Defines, src.Defines...)
-	dst.DBTypeMappings = append(dst.DBTypeMappings, src.DBTypeMappings...)
-	dst.GOTypeMappings = append(dst.GOTypeMappings, src.GOTypeMappings...)
-	if src.DBControl.DBName != "" {
-		dst.DBControl.DBName = src.DBControl.DBName
+		return out.writeFiles(options.OutputName, files, fileGenerator.IsGeneratedFile)
 	}
-	if src.DBControl.Host != "" {
-		dst.DBControl.Host = src.DBControl.Host
-	}
-	if src.DBControl.Password != "" {
-		dst.DBControl.Password = src.DBControl.Password
-	}
-	if src.DBControl.User != "" {
-		dst.DBControl.User = src.DBControl.User
+	code, err := codeGenerator.GenerateCode(model, options)
+	if err != nil {
+		return err
 	}
 
-	return dst
-}
-
-//
-// Generate domain model for selected language
-//
-func generateLanguageModel(options *common.Options, doc common.XMLDoc) {
-
-	codeGenerator := options.Language.GetModelGenerator()
-	code := codeGenerator.GenerateCode(doc, options)
-
 	if options.OutputName != "-" {
-		byteCode := []byte(code)
-		ioutil.WriteFile(options.OutputName, byteCode, 0644)
-	} else {
-		log.Printf("%s\n", code)
+		return out.write(options.OutputName, code)
+	}
+	if out.check {
+		out.skip("model")
+		return nil
 	}
+	log.Printf("%s\n", code)
+	return nil
 }
 
 //
 // generate persistence layer for selected language
 //
-func generatePersistence(options *common.Options, doc common.XMLDoc) {
+func generatePersistence(options *common.Options, model *common.Model, out *output) error {
 	crudGenerator := options.Language.GetCrudGenerator()
 	if crudGenerator != nil {
 		if options.Verbose > 0 {
 			log.Printf("Generating persistence code, saving to '%s'", options.OutputDBName)
-			log.Printf("  DB Control: %v\n", doc.DBControl)
+			log.Printf("  DB Control: %v\n", model.Doc.DBControl)
+		}
+		if options.SplitInFiles {
+			fileGenerator, err := splitGenerator(crudGenerator, "persistence", options)
+			if err != nil {
+				return err
+			}
+			files, err := fileGenerator.GenerateFiles(model, options)
+			if err != nil {
+				return err
+			}
+			if err := out.writeFiles(options.OutputDBName, files, fileGenerator.IsGeneratedFile); err != nil {
+				return err
+			}
+			return generateDBScript(options, model, out)
 		}
 		//var persistenceCode = generatePersistenceCode(doc, options.PersistenceClass, options.Filename, options.SplitInFiles, options.Converters, options.Verbose, options.OutputName)
-		var persistenceCode = crudGenerator.GenerateCode(doc, options)
-		persistenceByteCode := []byte(persistenceCode)
-		ioutil.WriteFile(options.OutputDBName, persistenceByteCode, 0644)
+		persistenceCode, err := crudGenerator.GenerateCode(model, options)
+		if err != nil {
+			return err
+		}
+		if err := out.write(options.OutputDBName, persistenceCode); err != nil {
+			return err
+		}
 	} else {
 		log.Printf("No Crud generator for language\n")
 	}
 
-	//
-	// Create DB Create/Alter script - this is dumped to STDOUT
-	//
+	return generateDBScript(options, model, out)
+}
+
+//
+// Create DB Create/Alter script - this is dumped to STDOUT unless a script file is given
+//
+func generateDBScript(options *common.Options, model *common.Model, out *output) error {
 	dbGenerator := options.Language.GetDBCreateGenerator()
 	if dbGenerator != nil {
-		var dbCreateCode = dbGenerator.GenerateCode(doc, options)
+		if options.SplitInFiles {
+			fileGenerator, err := splitGenerator(dbGenerator, "DB script", options)
+			if err != nil {
+				return err
+			}
+			if options.OutputSQLName == "" || options.OutputSQLName == "-" {
+				return usageErrorf("split in files (-s) needs a directory for the DB script (--sql-output)")
+			}
+			if options.Verbose > 0 && !out.check {
+				log.Printf("Saving DB script to '%s', a file per table\n", options.OutputSQLName)
+			}
+			files, err := fileGenerator.GenerateFiles(model, options)
+			if err != nil {
+				return err
+			}
+			return out.writeFiles(options.OutputSQLName, files, fileGenerator.IsGeneratedFile)
+		}
+		dbCreateCode, err := dbGenerator.GenerateCode(model, options)
+		if err != nil {
+			return err
+		}
+		if options.OutputSQLName != "" && options.OutputSQLName != "-" {
+			if options.Verbose > 0 && !out.check {
+				log.Printf("Saving DB script to '%s'\n", options.OutputSQLName)
+			}
+			return out.write(options.OutputSQLName, dbCreateCode+"\n")
+		}
+		if out.check {
+			out.skip("DB script")
+			return nil
+		}
 		if options.Verbose > 0 {
 			log.Printf("dbCreateCode:\n")
 		}
@@ -136,185 +148,266 @@ func generatePersistence(options *common.Options, doc common.XMLDoc) {
 	} else {
 		log.Printf("No DB Script Generator\n")
 	}
+	return nil
+}
+
+//
+// Returns the generator splitting its output in files (-s), an error if the generator of the language doesn't support it
+//
+func splitGenerator(generator common.Generator, what string, options *common.Options) (common.FileGenerator, error) {
+	fileGenerator, ok := generator.(common.FileGenerator)
+	if !ok {
+		return nil, fmt.Errorf("split in files (-s) is not supported by the %s %s generator", options.UseLanguage, what)
+	}
+	return fileGenerator, nil
+}
+
+//
+// Checks that the classes given for persistence (-p) are classes of the model and that the classes on the other side
+// of their many to many relations are given as well
+//
+func checkPersistenceClasses(options *common.Options, model *common.Model) error {
+	for _, className := range options.AllPersistenceClasses {
+		if className == "-" {
+			continue
+		}
+		if define := model.FindType(className); define == nil || !define.IsClass() {
+			return usageErrorf("unknown persistence class '%s', no such class in %s", className, options.Filename)
+		}
+	}
+	// the join table of a many to many relation references the tables of both classes
+	for _, define := range model.Types {
+		if !define.IsClass() || define.SkipPersistance || !options.IsPersistenceClass(define.Name) {
+			continue
+		}
+		for _, field := range define.Fields {
+			if field.Relation != common.RelationManyToMany || field.IsRemoved() || field.Related == nil {
+				continue
+			}
+			if target := field.Related.Target; !options.IsPersistenceClass(target.Name) {
+				return usageErrorf("persistence class '%s' has the many to many relation '%s', add '%s' to the persistence classes (-p %s,%s)",
+					define.Name, field, target.Name, define.Name, target.Name)
+			}
+		}
+	}
+	return nil
 }
 
-func printHelp() {
-	fmt.Printf("%s %s - XML Data Model to Language structure converter\n", Name, Version)
-	fmt.Println("Usage: modelgenerator [-sv] [-p <class>] [-f <num>] [-o <file/dir>] <inputfile>")
-	fmt.Println("General Options")
-	fmt.Println("  -f : From Version, generates any class/field matching >= specified version (0 means as virgin)")
-	fmt.Println("  -p : Generate persistence (use optional 'class' to specifiy which class for persistence, or '-' for all - default)")
-	fmt.Println("  -s : split each type in separate file")
-	fmt.Println("  -l : specify output language (go/cpp/ts)")
-	fmt.Println("  -m : override model member prefix (use '!' to drop it)")
-	fmt.Println("Domain Model Options")
-	fmt.Println("  -c : generate convertes (to/from XML/JSON)")
-	fmt.Println("  -g : disable getters/setters")
-	fmt.Println("  -o : specify output model file or '-' for stdout (default) ")
-	fmt.Println("  -M : generate marshalling code (defaults: CPP: off, GO: on, Typescript: on)")
-	fmt.Println("DB Layer Options")
-	fmt.Println("  -P : Table name prefix (default is 'nagini_se_')")
-	fmt.Println("  -d : Generate drop statements before create (default = false)")
-	fmt.Println("  -O : specify output database go file or dir (if split in multiple files is true), default is 'db.go'")
-	fmt.Println("  -v : increase verbose output (default 0 - none)")
-	fmt.Println("  -h : this page")
-	fmt.Println("inputfile : XML Data Model definition file")
-	fmt.Println("")
+//
+// Validates the document, prints all diagnostics and returns false if there were any errors
+//
+func validateDocument(options *common.Options, doc *common.XMLDoc) bool {
+	diagnostics := common.ValidateDocument(doc)
+	for _, diag := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s\n", diag)
+	}
+	if diagnostics.HasErrors() {
+		fmt.Fprintf(os.Stderr, "%d error(s) found in %s\n", diagnostics.ErrorCount(), options.Filename)
+		return false
+	}
+	if options.Verbose > 0 {
+		log.Printf("Validation ok, %d warning(s)\n", len(diagnostics))
+	}
+	return true
 }
 
-func getLanguage(name string) common.Language {
-	switch strings.ToLower(name) {
-	case "go":
-		fallthrough
-	case "golang":
-		log.Printf("Creating generators for GO\n")
-		return golang.CreateGoLanguage()
-	case "cpp":
-		fallthrough
-	case "c++":
-		log.Printf("Creating generators for C++\n")
-		return cpp.CreateCppLanguage()
-	case "typescript":
-		fallthrough
-	case "ts":
-		log.Printf("Creating generators for TS (TypeScript)\n")
-		return typescript.CreateTSLanguage()
-	}
-	log.Fatalf("No support for language: %s\n", name)
+//
+// Creates a model with an importer (import-sql, import-schema, extract) and writes it to the output, the output format
+// is given by the file extension. The model is validated, it is written even with errors so it can be fixed by hand,
+// but the import fails then.
+//
+func importModel(options *common.Options, output string, importer func() (common.XMLDoc, error)) error {
+	doc, err := importer()
+	if err != nil {
+		return err
+	}
+	diagnostics := common.ValidateDocument(&doc)
+	for _, diag := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s\n", diag)
+	}
+	data, err := common.FormatDocument(&doc, output)
+	if err != nil {
+		return err
+	}
+	if options.Verbose > 0 {
+		log.Printf("Imported %d define(s) from %s to %s\n", len(doc.Defines), doc.Filename, output)
+	}
+	if err := ioutil.WriteFile(output, data, 0644); err != nil {
+		return err
+	}
+	if diagnostics.HasErrors() {
+		fmt.Fprintf(os.Stderr, "%d error(s) found in %s\n", diagnostics.ErrorCount(), output)
+		return errFailed
+	}
 	return nil
 }
 
-func main() {
-	options := common.Options{
-		SplitInFiles:          false,
-		Converters:            false,
-		Verbose:               0,
-		DBTablePrefix:         "nagini_se_",
-		Filename:              "",
-		OutputName:            "-",
-		OutputDBName:          "db.go",
-		PersistenceClass:      "-",
-		AllPersistenceClasses: nil,
-		UseLanguage:           "go",
-		GettersAndSetters:     true,
-		DoPersistence:         false,
-		IsUpgrade:             false,
-		FromVersion:           0, // Always assume from version 0
-		GenerateDropStatement: false,
-		MemberPrefix:          "",
-		CPPJson:               false,
+//
+// Converts a single model file to another format (XML, YAML or JSON, given by the file extension), includes are not merged
+//
+func convertDocument(options *common.Options, input string, output string) error {
+	doc, err := common.ReadDocumentFile(input)
+	if err != nil {
+		return err
+	}
+	data, err := common.FormatDocument(&doc, output)
+	if err != nil {
+		return err
+	}
+	if options.Verbose > 0 {
+		log.Printf("Converting %s (%s) to %s (%s)\n", input, common.DocumentFormat(input), output, common.DocumentFormat(output))
 	}
+	return ioutil.WriteFile(output, data, 0644)
+}
 
-	if len(os.Args) > 1 {
-
-		for i := 0; i < len(os.Args); i++ {
-			arg := os.Args[i]
-			//log.Printf("Arg: %s\n", arg)
-			if arg[0] == '-' {
-				switch arg[1] {
-				case 's':
-					options.SplitInFiles = true
-					break
-				case 'c':
-					options.Converters = true
-					break
-				case 'd':
-					options.GenerateDropStatement = true
-					break
-				case 'l':
-					i++
-					options.UseLanguage = os.Args[i]
-					break
-				case 'm':
-					i++
-					options.MemberPrefix = os.Args[i]
-					break
-				case 'f':
-					i++
-					options.FromVersion, _ = strconv.Atoi(os.Args[i])
-					if options.FromVersion > 0 {
-						options.IsUpgrade = true
-					}
-					break
-				case 'g':
-					options.GettersAndSetters = false
-					break
-				case 'M':
-					options.CPPJson = true
-					break
-				case 'p':
-					options.DoPersistence = true
-					if os.Args[i+1][0] != '-' {
-						i++
-						options.AllPersistenceClasses = strings.Split(os.Args[i], ",")
-						options.PersistenceClass = options.AllPersistenceClasses[0] //os.Args[i]
-					} else {
-						i++
-						options.PersistenceClass = os.Args[i]
-					}
-					break
-				case 'P':
-					i++
-					options.DBTablePrefix = os.Args[i]
-					break
-				case 'v':
-					options.Verbose++
-					break
-				case 'o':
-					i++
-					options.OutputName = os.Args[i]
-					break
-				case 'O':
-					i++
-					options.OutputDBName = os.Args[i]
-					break
-				case 'h':
-					printHelp()
-					return
-				default:
-					log.Printf("Error: Unknown argument %s\n", arg)
-					printHelp()
-				}
-			} else {
-				options.Filename = arg
-			}
+//
+// Writes a project file for the model and a model skeleton if the model doesn't exist yet
+//
+func initProject(options *common.Options, model string, force bool) error {
+	if _, err := os.Stat(common.ProjectFileName); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", common.ProjectFileName)
+	}
+	if _, err := os.Stat(model); os.IsNotExist(err) {
+		doc := common.XMLDoc{
+			Namespace: "model",
+			Defines: []common.XMLDefine{{Type: "class", Name: "Item", Fields: []common.XMLDataTypeField{
+				{Name: "ItemID", Type: "int64", PrimaryKey: true},
+				{Name: "Name", Type: "string"},
+			}}},
+		}
+		data, err := common.FormatDocument(&doc, model)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(model, data, 0644); err != nil {
+			return err
 		}
+		log.Printf("Created model %s\n", model)
 	}
+	project := fmt.Sprintf(`# %s project, run 'modelgenerator' in this directory to generate all targets
+model: %s
+tableprefix: %s
+targets:
+  - language: go
+    output: model.go
+    dboutput: db.go
+    sqloutput: create.sql
+    persistence: "-"
+`, Name, model, options.DBTablePrefix)
+	if err := ioutil.WriteFile(common.ProjectFileName, []byte(project), 0644); err != nil {
+		return err
+	}
+	log.Printf("Created project %s\n", common.ProjectFileName)
+	return nil
+}
 
-	if options.Filename == "" {
-		printHelp()
-		return
+//
+// Loads two versions of a model and prints the differences, fails if there are any
+//
+func diffModels(options *common.Options, from string, to string) error {
+	fromDoc, err := loadDocument(options, from)
+	if err != nil {
+		return errFailed
+	}
+	toDoc, err := loadDocument(options, to)
+	if err != nil {
+		return errFailed
+	}
+	lines := common.DiffDocuments(&fromDoc, &toDoc)
+	for _, line := range lines {
+		fmt.Println(line)
 	}
+	if len(lines) > 0 {
+		return errFailed
+	}
+	return nil
+}
 
-	intputFilePath, err := filepath.Abs(options.Filename)
-	options.DocumentRootDirectory = filepath.Dir(intputFilePath)
+//
+// Prints the registered languages with their capabilities and options
+//
+func listLanguages() {
+	for _, info := range common.Languages() {
+		name := info.Name
+		if len(info.Aliases) > 0 {
+			name += " (" + strings.Join(info.Aliases, ", ") + ")"
+		}
+		fmt.Printf("%-20s : %s [%s]\n", name, info.Description, strings.Join(info.Capabilities(), ", "))
+		for _, option := range info.Options {
+			names := option.Name
+			if option.Arg != "" {
+				names += "=<" + option.Arg + ">"
+			}
+			fmt.Printf("  -X %-18s : %s\n", names, option.Help)
+		}
+	}
+}
 
-	if options.Verbose > 0 {
+// errFailed is returned when the reason of the failure is already reported (like the diagnostics of a model)
+var errFailed = errors.New("failed")
 
-		log.Printf("%s %s\n", Name, Version)
+// errNoDBScript is returned when a DB script is requested for a language without a DB layer
+var errNoDBScript = errors.New("no DB script generator for the language")
+
+// What a run of a model generates
+const (
+	runGenerate = iota // the model code and the persistence (-p)
+	runValidate        // only validate the model
+	runDBScript        // only the DB create script (or upgrade script with -f)
+	runMigrate         // only the DB upgrade script, -f is required
+)
+
+//
+// Loads, validates and generates the model with the given options
+//
+func generate(options *common.Options, mode int) error {
+	intputFilePath, _ := filepath.Abs(options.Filename)
+	options.DocumentRootDirectory = filepath.Dir(intputFilePath)
 
+	if options.Verbose > 0 {
 		log.Printf("Processing file: %s\n", options.Filename)
 		log.Printf("With root directory: %s\n", filepath.Dir(intputFilePath))
 		log.Printf("Generating from version: %d\n", options.FromVersion)
 		log.Printf("Output language: %s\n", options.UseLanguage)
 	}
 
-	options.Language = getLanguage(options.UseLanguage)
-	if options.Language == nil {
-		log.Fatalf("No implementation for '%s'\n", options.UseLanguage)
+	if mode == runMigrate && !options.IsUpgrade {
+		return usageErrorf("migrate needs the version to upgrade from (-f <num>)")
 	}
 
-	//fmt.Printf("Processing file: %s\n", filename)
-	//fmt.Println("Unmarshal XML")
+	doc, err := loadDocument(options, options.Filename)
+	if err != nil {
+		return errFailed
+	}
 
-	doc, err := loadDocument(&options, options.Filename)
+	if !validateDocument(options, &doc) {
+		return errFailed
+	}
+	if mode == runValidate {
+		return nil
+	}
 
-	if err != nil {
-		log.Fatalln("Failed to load document: " + options.Filename)
-		return
+	language := common.FindLanguage(options.UseLanguage)
+	if language == nil {
+		return fmt.Errorf("no support for language: %s (languages: %s)", options.UseLanguage, common.LanguageNames())
+	}
+	if err := language.ApplyOptions(options); err != nil {
+		return err
+	}
+	if options.Verbose > 0 {
+		log.Printf("Creating generators for %s\n", language.Name)
+	}
+	options.Language = language.Create()
+	dbScriptOnly := mode == runDBScript || mode == runMigrate
+	if dbScriptOnly && options.Language.GetDBCreateGenerator() == nil {
+		return errNoDBScript
 	}
 
 	options.CurrentDoc = &doc // set this so we have access
+	model := common.BuildModel(&doc)
+	if err := checkPersistenceClasses(options, model); err != nil {
+		return err
+	}
 
 	if options.Verbose > 0 {
 		log.Printf("DB Typemappoings: %d\n", len(doc.DBTypeMappings))
@@ -322,9 +415,194 @@ func main() {
 		log.Println("File read ok, generating data model code...")
 	}
 
-	generateLanguageModel(&options, doc)
+	out := newOutput(options)
+	if dbScriptOnly {
+		if err := generateDBScript(options, model, out); err != nil {
+			return err
+		}
+		if err := out.clean(options.Filename); err != nil {
+			return err
+		}
+		return out.result()
+	}
+
+	if err := generateLanguageModel(options, model, out); err != nil {
+		return err
+	}
 
 	if options.DoPersistence {
-		generatePersistence(&options, doc)
+		if err := generatePersistence(options, model, out); err != nil {
+			return err
+		}
+	}
+	if err := out.clean(options.Filename); err != nil {
+		return err
+	}
+	return out.result()
+}
+
+//
+// Runs the model given on the command line or all targets of the project file
+//
+func runTargets(cl *commandLine, mode int) error {
+	if len(cl.args) > 0 {
+		cl.set(func(options *common.Options) { options.Filename = cl.args[0] })
+	}
+	if cl.projectFile == "" && cl.options.Filename == "" {
+		if _, err := os.Stat(common.ProjectFileName); err != nil {
+			return usageErrorf("no model given and no %s found", common.ProjectFileName)
+		}
+		cl.projectFile = common.ProjectFileName
+	}
+
+	if cl.projectFile == "" {
+		err := generate(&cl.options, mode)
+		if err == errNoDBScript {
+			return fmt.Errorf("no DB script generator for language: %s", cl.options.UseLanguage)
+		}
+		return err
+	}
+
+	project, err := common.LoadProject(cl.projectFile)
+	if err != nil {
+		return err
+	}
+	failed := false
+	ran := 0
+	for i := range project.Targets {
+		target := &project.Targets[i]
+		options := cl.options
+		project.Apply(target, &options)
+		for _, apply := range cl.overrides {
+			apply(&options)
+		}
+		if cl.options.Verbose > 0 {
+			log.Printf("Generating target '%s' of %s\n", target.Name, cl.projectFile)
+		}
+		err := generate(&options, mode)
+		if err == errNoDBScript {
+			// only the targets of languages with a DB layer create a script
+			continue
+		}
+		ran++
+		if err != nil {
+			if err != errFailed {
+				fmt.Fprintf(os.Stderr, "Error: target '%s': %s\n", target.Name, err)
+			}
+			failed = true
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	if ran == 0 {
+		return fmt.Errorf("no target of %s has a DB script generator", cl.projectFile)
+	}
+	return nil
+}
+
+var cliCommands = []cliCommand{
+	{name: "generate", args: "[<model>]", help: "generate the model code, the persistence (-p) and the DB create script", maxArgs: 1,
+		run: func(cl *commandLine) error {
+			return runTargets(cl, runGenerate)
+		}},
+	{name: "validate", args: "[<model>]", help: "only validate the model, exits with non-zero exit code on errors", maxArgs: 1,
+		run: func(cl *commandLine) error {
+			return runTargets(cl, runValidate)
+		}},
+	{name: "sql", args: "[<model>]", help: "only generate the DB create script", maxArgs: 1,
+		run: func(cl *commandLine) error {
+			return runTargets(cl, runDBScript)
+		}},
+	{name: "migrate", args: "[<model>]", help: "only generate the DB upgrade script from the version given with -f", maxArgs: 1,
+		run: func(cl *commandLine) error {
+			return runTargets(cl, runMigrate)
+		}},
+	{name: "diff", args: "<old model> <new model>", help: "print the defines, fields and indexes added, removed or changed, exits with 1 if there are differences", minArgs: 2, maxArgs: 2,
+		run: func(cl *commandLine) error {
+			return diffModels(&cl.options, cl.args[0], cl.args[1])
+		}},
+	{name: "init", args: "[<model>]", help: "create a modelgen.yaml project file (and the model if it doesn't exist, default model.xml)", maxArgs: 1,
+		run: func(cl *commandLine) error {
+			model := "model.xml"
+			if len(cl.args) > 0 {
+				model = cl.args[0]
+			}
+			return initProject(&cl.options, model, cl.force)
+		}},
+	{name: "languages", help: "list the languages with what they generate and their options (-X)",
+		run: func(cl *commandLine) error {
+			listLanguages()
+			return nil
+		}},
+	{name: "convert", args: "<model> <output>", help: "convert a model file between XML, YAML and JSON (given by the file extensions)", minArgs: 2, maxArgs: 2,
+		run: func(cl *commandLine) error {
+			return convertDocument(&cl.options, cl.args[0], cl.args[1])
+		}},
+	{name: "import-sql", args: "<sqlfile> <output>", help: "create a model from a MySQL DDL dump (mysqldump --no-data), the table prefix (-P) is stripped", minArgs: 2, maxArgs: 2,
+		run: func(cl *commandLine) error {
+			return importModel(&cl.options, cl.args[1], func() (common.XMLDoc, error) {
+				return common.ImportSQLFile(cl.args[0], &cl.options)
+			})
+		}},
+	{name: "import-schema", args: "<schemafile> <output>", help: "create a model from a JSON Schema or the components/schemas of an OpenAPI 3 file", minArgs: 2, maxArgs: 2,
+		run: func(cl *commandLine) error {
+			return importModel(&cl.options, cl.args[1], func() (common.XMLDoc, error) {
+				return common.ImportSchemaFile(cl.args[0], &cl.options)
+			})
+		}},
+	{name: "extract", args: "<gofile/dir> <output> [<type>...]", help: "create a model from Go struct types (all structs if no type is given) and the int64 enums they use", minArgs: 2, maxArgs: -1,
+		run: func(cl *commandLine) error {
+			return importModel(&cl.options, cl.args[1], func() (common.XMLDoc, error) {
+				return common.ExtractGoFile(cl.args[0], cl.args[2:], &cl.options)
+			})
+		}},
+}
+
+func main() {
+	options := common.Options{
+		SplitInFiles:          false,
+		Converters:            false,
+		Verbose:               0,
+		DBTablePrefix:         "nagini_se_",
+		Filename:              "",
+		OutputName:            "-",
+		OutputDBName:          "db.go",
+		PersistenceClass:      "-",
+		AllPersistenceClasses: nil,
+		UseLanguage:           "go",
+		GettersAndSetters:     true,
+		DoPersistence:         false,
+		IsUpgrade:             false,
+		FromVersion:           0, // Always assume from version 0
+		GenerateDropStatement: false,
+		MemberPrefix:          "",
+		CPPJson:               false,
+	}
+
+	cl, err := parseCommandLine(os.Args[1:], options)
+	if err != nil {
+		reportError(err)
+	}
+	if cl.help {
+		printHelp()
+		return
+	}
+	if cl.version {
+		fmt.Printf("%s %s\n", Name, Version)
+		return
+	}
+	if len(os.Args) == 1 {
+		if _, err := os.Stat(common.ProjectFileName); err != nil {
+			printHelp()
+			os.Exit(exitUsage)
+		}
+	}
+
+	if cl.options.Verbose > 0 {
+		log.Printf("%s %s\n", Name, Version)
+	}
+	if err := cl.command.run(cl); err != nil {
+		reportError(err)
 	}
 }
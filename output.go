@@ -0,0 +1,170 @@
+package main
+
+//
+// Writes the generated files, in check mode (--check) the generated code is compared with the files instead
+//
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"modelgenerator/common"
+)
+
+// output of a generator run, collects the files which are not up to date in check mode
+type output struct {
+	check   bool
+	verbose int
+	drifted []string
+	// the directories of split outputs (-s) with the names of the generators writing to them and the files written, see clean
+	splitDirs map[string][]func(name string) bool
+	written   map[string]bool
+}
+
+func newOutput(options *common.Options) *output {
+	return &output{
+		check:     options.CheckOutput,
+		verbose:   options.Verbose,
+		splitDirs: make(map[string][]func(name string) bool),
+		written:   make(map[string]bool),
+	}
+}
+
+//
+// Writes the code to the file, or in check mode prints the unified diff between the file and the code
+//
+func (out *output) write(filename string, code string) error {
+	if !out.check {
+		return ioutil.WriteFile(filename, []byte(code), 0644)
+	}
+
+	current, err := ioutil.ReadFile(filename)
+	fromName := filename
+	if os.IsNotExist(err) {
+		fromName = "/dev/null"
+	} else if err != nil {
+		return err
+	}
+	diff := common.UnifiedDiff(fromName, filename+" (generated)", string(current), code)
+	if diff == "" {
+		if out.verbose > 0 {
+			log.Printf("'%s' is up to date\n", filename)
+		}
+		return nil
+	}
+	fmt.Print(diff)
+	out.drifted = append(out.drifted, filename)
+	return nil
+}
+
+//
+// Writes the files of a split output (-s) to the directory, the files of the directory which were generated from
+// the model before but aren't written again are removed by clean. isGenerated tells the names of the generator's files,
+// files of other generators sharing the directory are kept
+//
+func (out *output) writeFiles(dir string, files []common.GeneratedFile, isGenerated func(name string) bool) error {
+	if dir == "" || dir == "-" {
+		return usageErrorf("split in files (-s) needs an output directory")
+	}
+	if !out.check {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	out.splitDirs[dir] = append(out.splitDirs[dir], isGenerated)
+	for _, file := range files {
+		filename := filepath.Join(dir, file.Name)
+		out.written[filename] = true
+		if err := out.write(filename, file.Code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// Removes the stale files of the split output directories, like the file of a define removed from the model.
+// Only files generated from the same model (the 'data model source' line of the header) by a generator which wrote
+// to the directory in this run are removed.
+//
+func (out *output) clean(source string) error {
+	dirs := []string{}
+	for dir := range out.splitDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			filename := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || out.written[filename] || !generatedBy(out.splitDirs[dir], entry.Name()) {
+				continue
+			}
+			data, err := ioutil.ReadFile(filename)
+			if err != nil || !generatedFrom(string(data), source) {
+				continue
+			}
+			if out.check {
+				fmt.Print(common.UnifiedDiff(filename, "/dev/null", string(data), ""))
+				out.drifted = append(out.drifted, filename)
+				continue
+			}
+			if out.verbose > 0 {
+				log.Printf("Removing '%s', it is no longer generated\n", filename)
+			}
+			if err := os.Remove(filename); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// generatedBy returns true if one of the generators writes files with the name
+func generatedBy(generators []func(name string) bool, name string) bool {
+	for _, isGenerated := range generators {
+		if isGenerated(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedFrom returns true if the header of a generated file names the model as source
+func generatedFrom(code string, source string) bool {
+	lines := strings.SplitN(code, "\n", 20)
+	for _, line := range lines[:len(lines)-1] {
+		if strings.HasSuffix(strings.TrimSpace(line), "data model source = "+source) {
+			return true
+		}
+	}
+	return false
+}
+
+// skip notes output which can't be checked, like code written to stdout
+func (out *output) skip(what string) {
+	if out.verbose > 0 {
+		log.Printf("Not checking the %s, it is written to stdout\n", what)
+	}
+}
+
+// result returns errFailed if files are not up to date, the differences have been printed
+func (out *output) result() error {
+	if len(out.drifted) == 0 {
+		return nil
+	}
+	for _, filename := range out.drifted {
+		fmt.Fprintf(os.Stderr, "'%s' is not up to date, regenerate it\n", filename)
+	}
+	return errFailed
+}